@@ -0,0 +1,36 @@
+package main
+
+import (
+	"time"
+
+	"github.com/charmbracelet/bubbletea"
+)
+
+// statusEvery is how often --accessible logs how far along the run is.
+const statusEvery = 30 * time.Second
+
+// statusDue is sent when --accessible should log the status again.
+type statusDue struct{}
+
+func nextStatus() tea.Cmd {
+	return tea.Tick(statusEvery, func(time.Time) tea.Msg { return statusDue{} })
+}
+
+// progressLine describes how far along the run is in a sentence, for screen
+// readers.
+func (m model) progressLine() string {
+	s := tr("%d of %d repos done, %d failed, %d running", m.index, len(m.directories), len(m.failures), len(m.started))
+	if left, ok := m.eta(); ok {
+		s += tr(", about %s left", left.Round(time.Second))
+	}
+
+	return s + "."
+}
+
+// accessibleStyles spells out what the symbols in lines about repos mean,
+// which screen readers would read as "check mark" or not at all.
+func accessibleStyles(st styles) styles {
+	st.checkmark = st.checkmark.SetString("Done:")
+	st.cross = st.cross.SetString("Failed:")
+	return st
+}
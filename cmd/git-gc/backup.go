@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// backups writes a bundle of all refs of a repo before a task that could lose
+// data runs in it, so mistaken pruning can be recovered with e.g.
+// `git fetch FILE 'refs/*:refs/recovered/*'`. Next to the bundle, a directory
+// of the same name with a .refs extension keeps a copy of the repo's reflogs
+// and packed-refs, which a bundle can't hold.
+type backups struct {
+	dir       string        // where bundles are kept, "" disables backups
+	retention time.Duration // delete older bundles of a repo, 0 keeps them forever
+}
+
+// repoDir returns where the bundles of repo are kept. The path hash tells
+// apart repos with the same name.
+func (b backups) repoDir(repo string) string {
+	sum := sha256.Sum256([]byte(repo))
+	return filepath.Join(b.dir, filepath.Base(repo)+"-"+hex.EncodeToString(sum[:4]))
+}
+
+// create bundles the refs of repo, copies its reflogs and expires its old
+// backups, stopping git like a task when ctx is done. It returns a note for the repo, which is
+// empty when there's nothing to back up.
+func (b backups) create(ctx context.Context, repo string) (string, error) {
+	if b.dir == "" {
+		return "", nil
+	}
+
+	cmd, exited := groupCommand(ctx, repo, gitPath, "-C", repo, "for-each-ref", "--format=%(refname)")
+	refs, err := cmd.Output()
+	exited()
+	if ctx.Err() != nil {
+		return "", fmt.Errorf("could not list refs to back up: %w", contextError(ctx))
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("could not list refs to back up: %w", err)
+	}
+
+	// git refuses to create an empty bundle
+	if strings.TrimSpace(string(refs)) == "" {
+		return "", nil
+	}
+
+	dir := b.repoDir(repo)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("could not create backup dir: %w", err)
+	}
+
+	path := filepath.Join(dir, time.Now().UTC().Format("20060102T150405Z")+".bundle")
+	// Without --quiet, which needs git 2.25; there's no progress to a pipe
+	cmd, exited = groupCommand(ctx, repo, gitPath, "-C", repo, "bundle", "create", path, "--all")
+	out, err := cmd.CombinedOutput()
+	exited()
+	if err != nil {
+		// Don't leave a partial bundle behind to be mistaken for a backup
+		_ = os.Remove(path)
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("could not back up refs: %w", contextError(ctx))
+		}
+
+		return "", fmt.Errorf("could not back up refs: %w", gitError(err, string(out)))
+	}
+
+	if err := copyReflogs(ctx, repo, refsBackup(path)); err != nil {
+		_ = os.Remove(path)
+		_ = os.RemoveAll(refsBackup(path))
+		return "", err
+	}
+
+	if err := b.expire(dir); err != nil {
+		return "", err
+	}
+
+	return "backed up to " + path, nil
+}
+
+// refsBackup returns the directory the reflogs and packed-refs are copied to
+// along with bundle.
+func refsBackup(bundle string) string {
+	return strings.TrimSuffix(bundle, ".bundle") + ".refs"
+}
+
+// copyReflogs copies the reflogs and packed-refs of repo to dst. They're
+// shared by the repo's worktrees, so they're in its common git dir.
+func copyReflogs(ctx context.Context, repo, dst string) error {
+	out, err := gitOutput(ctx, repo, "rev-parse", "--git-common-dir")
+	if err != nil {
+		return fmt.Errorf("could not find reflogs to back up: %w", err)
+	}
+
+	gitDir := strings.TrimSpace(string(out))
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(repo, gitDir)
+	}
+
+	if err := os.MkdirAll(dst, 0o755); err != nil {
+		return fmt.Errorf("could not create backup dir: %w", err)
+	}
+
+	for _, name := range []string{"logs", "packed-refs"} {
+		if err := copyTree(filepath.Join(gitDir, name), filepath.Join(dst, name)); err != nil {
+			return fmt.Errorf("could not back up reflogs: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// copyTree copies the file or directory src to dst. A missing src, e.g. a
+// repo without packed refs, copies nothing.
+func copyTree(src, dst string) error {
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0o755)
+		}
+
+		return copyFile(path, target)
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	return err
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(out, in)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+// expire deletes the backups in dir older than the retention, always keeping
+// the newest one.
+func (b backups) expire(dir string) error {
+	if b.retention <= 0 {
+		return nil
+	}
+
+	bundles, err := filepath.Glob(filepath.Join(dir, "*.bundle"))
+	if err != nil || len(bundles) == 0 {
+		return err
+	}
+
+	// Names are timestamps, so they sort oldest first
+	slices.Sort(bundles)
+	cutoff := time.Now().Add(-b.retention)
+	for _, path := range bundles[:len(bundles)-1] {
+		info, err := os.Stat(path)
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("could not expire backup: %w", err)
+		}
+
+		if err := os.RemoveAll(refsBackup(path)); err != nil {
+			return fmt.Errorf("could not expire backup: %w", err)
+		}
+	}
+
+	return nil
+}
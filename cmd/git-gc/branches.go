@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+)
+
+// pruneGoneMode is how the prune-gone task deletes local branches whose
+// upstream was deleted.
+type pruneGoneMode string
+
+const (
+	pruneGoneOff     pruneGoneMode = "off"
+	pruneGoneDryRun  pruneGoneMode = "dry-run" // only list them
+	pruneGoneConfirm pruneGoneMode = "confirm" // ask before deleting them in each repo
+	pruneGoneYes     pruneGoneMode = "yes"     // delete them without asking
+)
+
+var pruneGoneModes = []pruneGoneMode{pruneGoneOff, pruneGoneDryRun, pruneGoneConfirm, pruneGoneYes}
+
+func parsePruneGoneMode(s string) (pruneGoneMode, error) {
+	for _, mode := range pruneGoneModes {
+		if string(mode) == s {
+			return mode, nil
+		}
+	}
+
+	return "", fmt.Errorf("unknown mode %q (available: %v)", s, pruneGoneModes)
+}
+
+// goneBranches returns the local branches whose upstream no longer exists,
+// split by whether they're merged into HEAD, which is what `git branch -d`
+// checks once the upstream is gone. The checked out branch is left out
+// since git refuses to delete it.
+func goneBranches(ctx context.Context, dir string) (merged, unmerged []string, err error) {
+	out, err := gitOutput(
+		ctx, dir, "for-each-ref",
+		"--format=%(HEAD)%09%(refname:short)%09%(upstream:track)",
+		"refs/heads",
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not list branches: %w", err)
+	}
+
+	mergedOut, err := gitOutput(ctx, dir, "for-each-ref", "--merged=HEAD", "--format=%(refname:short)", "refs/heads")
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not list merged branches: %w", err)
+	}
+
+	isMerged := make(map[string]bool)
+	for _, branch := range strings.Split(string(mergedOut), "\n") {
+		isMerged[branch] = true
+	}
+
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		// Tabs can't appear in branch names, unlike most other separators
+		head, rest, _ := strings.Cut(sc.Text(), "\t")
+		branch, track, _ := strings.Cut(rest, "\t")
+		switch {
+		case head == "*" || track != "[gone]":
+		case isMerged[branch]:
+			merged = append(merged, branch)
+		default:
+			unmerged = append(unmerged, branch)
+		}
+	}
+
+	return merged, unmerged, nil
+}
+
+// pruneGoneTask returns the task deleting gone branches. Only the ones
+// merged into HEAD are deleted, with `git branch -d`, unless unmerged is set,
+// which deletes the others too with `git branch -D`, e.g. after squash
+// merges.
+func pruneGoneTask(mode pruneGoneMode, b backups, unmerged bool) task {
+	return task{
+		name: "prune-gone",
+		desc: "gone branch pruning",
+		kind: kindDisk,
+		command: func(ctx context.Context, dir string) (invocation, error) {
+			branches, rest, err := goneBranches(ctx, dir)
+			if err != nil {
+				return invocation{}, err
+			}
+
+			del, kept := "-d", ""
+			if unmerged {
+				branches, del = append(branches, rest...), "-D"
+			} else if len(rest) > 0 {
+				kept = "kept unmerged gone branches, see --prune-gone-unmerged: " + strings.Join(rest, ", ")
+			}
+
+			if len(branches) == 0 {
+				return invocation{note: kept}, nil
+			}
+
+			list := strings.Join(branches, ", ")
+			if mode == pruneGoneDryRun {
+				return invocation{note: joinNotes("would delete gone branches: "+list, kept)}, nil
+			}
+
+			inv := invocation{
+				argv: append([]string{gitPath, "branch", del, "--"}, branches...),
+				note: joinNotes("deleted gone branches: "+list, kept),
+				beforeRun: func(ctx context.Context) (string, error) {
+					return b.create(ctx, dir)
+				},
+			}
+			if mode == pruneGoneConfirm {
+				inv.confirm = fmt.Sprintf("Delete %d gone branches in %s (%s)?", len(branches), dir, list)
+			}
+
+			return inv, nil
+		},
+	}
+}
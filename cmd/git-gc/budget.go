@@ -0,0 +1,41 @@
+package main
+
+import (
+	"slices"
+	"time"
+)
+
+// budget caps how much a run does. Once it's used up, no new repos start,
+// the running ones finish, and the rest is left for the next run.
+type budget struct {
+	maxRepos int       // 0 for no limit
+	deadline time.Time // zero for no limit
+}
+
+// budgetExpired is sent when the budget's deadline passes.
+type budgetExpired struct{}
+
+// exhausted reports whether no new repo may start after begun repos did.
+func (b budget) exhausted(begun int, now time.Time) bool {
+	return (b.maxRepos > 0 && begun >= b.maxRepos) || (!b.deadline.IsZero() && !now.Before(b.deadline))
+}
+
+// prioritize moves the dirs that are in first to the front, keeping the order
+// of both parts otherwise.
+func prioritize(dirs, first []string) {
+	isFirst := make(map[string]bool, len(first))
+	for _, dir := range first {
+		isFirst[dir] = true
+	}
+
+	slices.SortStableFunc(dirs, func(a, b string) int {
+		switch inA, inB := isFirst[a], isFirst[b]; {
+		case inA && !inB:
+			return -1
+		case inB && !inA:
+			return 1
+		default:
+			return 0
+		}
+	})
+}
@@ -0,0 +1,114 @@
+package main
+
+// catalogJa translates the messages to Japanese.
+var catalogJa = map[string]string{
+	// The summary
+	"Stopped! Ran %s on %d of %d repos.":                                          "中断しました。%[3]d 個中 %[2]d 個のリポジトリで%[1]sを実行しました。",
+	"Stopped at the first failure because of --fail-fast.":                        "--fail-fast のため、最初の失敗で中断しました。",
+	"Done! Ran %s on %d repos.":                                                   "完了しました。%[2]d 個のリポジトリで%[1]sを実行しました。",
+	"Out of budget, left %d repos for the next run.":                              "予算を使い切ったため、%d 個のリポジトリを次回に残しました。",
+	"Processed %d repos: %d succeeded, %d failed, %d skipped.":                    "%d 個のリポジトリを処理しました: 成功 %d、失敗 %d、スキップ %d。",
+	"Took %s, %s per repo on average, %.1f repos per minute.":                     "所要時間 %s、リポジトリあたり平均 %s、毎分 %.1f 個。",
+	"Freed %s in total.":                                                          "合計 %s を解放しました。",
+	"Grew by %s in total.":                                                        "合計 %s 増えました。",
+	"Ran %d failed repos again, %d succeeded the second time.":                    "失敗した %d 個のリポジトリを再実行し、%d 個が2回目に成功しました。",
+	"%s on %d of %d repos in %s, %d failed, %d skipped, %d left for the next run": "%[3]d 個中 %[2]d 個のリポジトリで%[1]s (%[4]s)、失敗 %[5]d、スキップ %[6]d、次回に残したもの %[7]d",
+	"Ran %s":                          "実行しました: %s",
+	"Failed %s: %s: %v":               "失敗しました %s: %s: %v",
+	"git-gc is done":                  "git-gc が完了しました",
+	"git-gc is done, %d repos failed": "git-gc が完了しました。%d 個のリポジトリが失敗しました",
+	"Press r to run the %d failed repos again, +/- for more or fewer parallel tasks (%s), any other key to quit.": "r で失敗した %d 個のリポジトリを再実行、+/- で並列数を変更 (%s)、その他のキーで終了します。",
+	"Slowest repos:":    "時間のかかったリポジトリ:",
+	"%d failed repos:":  "失敗したリポジトリ %d 個:",
+	"%d corrupt repos:": "破損したリポジトリ %d 個:",
+
+	// What the tasks do
+	"fetch":               "フェッチ",
+	"garbage collection":  "ガベージコレクション",
+	"repack":              "再パック",
+	"verification":        "検証",
+	"remote prune":        "リモートの整理",
+	"lfs prune":           "LFS の整理",
+	"gone branch pruning": "削除済みブランチの整理",
+	"exec":                "コマンドの実行",
+
+	// The line with the progress bar
+	"Cleaning %s %d/%d complete, %s": "%s を処理中 %d/%d 完了、%s",
+	", %s elapsed":                   "、経過 %s",
+	", %.1f repos/min":               "、毎分 %.1f 個",
+	", ~%s left":                     "、残り約 %s",
+	"repos...":                       "リポジトリ...",
+	" and %d more":                   " ほか %d 個",
+	"and %d more":                    "ほか %d 個",
+	"%d/%d done":                     "%d/%d 完了",
+	", %d failed":                    "、失敗 %d",
+	"%d parallel":                    "並列 %d",
+	"%d/%d parallel net/disk":        "並列 %d/%d (ネットワーク/ディスク)",
+	" (auto)":                        " (自動)",
+
+	// For screen readers
+	"%d of %d repos done, %d failed, %d running": "%[2]d 個中 %[1]d 個のリポジトリが完了、失敗 %[3]d、実行中 %[4]d",
+	", about %s left": "、残り約 %s",
+
+	// Prompts above it
+	"Killing %d running tasks...":                                           "実行中の %d 個のタスクを強制終了しています...",
+	"Stopping %d running tasks, press q again to kill them...":              "実行中の %d 個のタスクを停止しています。もう一度 q を押すと強制終了します...",
+	"Waiting for %d running tasks to finish, press q again to stop them...": "実行中の %d 個のタスクの完了を待っています。もう一度 q を押すと停止します...",
+	"Abort the run? ":                                          "実行を中止しますか? ",
+	"Running tasks will be stopped.":                           "実行中のタスクは停止されます。",
+	"Running tasks will finish first.":                         "実行中のタスクの完了を待ちます。",
+	"Add a root or repo: ":                                     "追加するルートまたはリポジトリ: ",
+	" (enter to add, esc to cancel)":                           " (enter で追加、esc でキャンセル)",
+	"Filter: ":                                                 "フィルター: ",
+	" (enter to keep, esc to clear)":                           " (enter で確定、esc で解除)",
+	" (%d more waiting)":                                       " (ほかに %d 件が待機中)",
+	"%s looks hung, nothing happened for %s":                   "%s が止まっているようです。%s の間、何も起きていません",
+	", press k to kill it and skip the repo":                   "。k を押すと強制終了してリポジトリをスキップします",
+	" (%d more)":                                               " (ほかに %d 件)",
+	" No new tasks start, press p to resume":                   " 新しいタスクは開始されません。p で再開します",
+	" The running tasks are suspended, press p to resume":      " 実行中のタスクは一時停止しています。p で再開します",
+	"Showing the repos matching %q, / to change, esc to clear": "%q に一致するリポジトリを表示しています。/ で変更、esc で解除",
+	" selected, o to open it, c to copy it, up/down for another, esc to unselect": " を選択中。o で開く、c でコピー、上下キーで別のリポジトリ、esc で解除",
+	"Copied %s to the clipboard": "%s をクリップボードにコピーしました",
+	"the path of %s":             "%s のパス",
+	"the error of %s":            "%s のエラー",
+	"Could not open %s: %s":      "%s を開けませんでした: %s",
+	"Sorted by %s, S to change":  "%s順に並べ替えています。S で変更",
+	"duration":                   "所要時間",
+	"space freed":                "解放した容量",
+	"status":                     "状態",
+
+	// Why no new tasks start
+	"Paused":                               "一時停止中",
+	"Starting in %s":                       "%s 後に開始します",
+	"Checking whether the machine is idle": "マシンがアイドル状態か確認しています",
+	"Checking the power source":            "電源を確認しています",
+	"Waiting for the machine to be idle, it's in use":  "マシンがアイドル状態になるのを待っています。使用中です",
+	"Waiting for the machine to be idle, load is %.1f": "マシンがアイドル状態になるのを待っています。負荷は %.1f です",
+	"Waiting while the machine is %s":                  "マシンが%sのため待機しています",
+	"on battery":                                       "バッテリー駆動中",
+	"in low power mode":                                "省電力モード",
+
+	// Replies to git-gc status and the other commands of the daemon
+	"idle, next run at %s": "待機中、次回の実行は %s",
+	"looking for repos":    "リポジトリを探しています",
+	"paused, %s":           "一時停止中、%s",
+	"running":              "実行中",
+	"running, %d/%d repos complete, %d tasks running, %s": "実行中、%d/%d 個のリポジトリが完了、%d 個のタスクを実行中、%s",
+	", %s":            "、%s",
+	"Last run: %s":    "前回の実行: %s",
+	"Already running": "すでに実行中です",
+	"Starting a run":  "実行を開始します",
+	"Adding %s to the run once it has found its repos": "リポジトリを探し終えたら %s を実行に追加します",
+	"No run in progress to add %s to":                  "%s を追加する実行がありません",
+	"Looking for repos in %s to add to the run":        "実行に追加するリポジトリを %s で探しています",
+	"Paused, no new tasks start until unpaused":        "一時停止しました。再開するまで新しいタスクは開始されません",
+	"Unpaused":           "再開しました",
+	"Unknown command %q": "不明なコマンド %q",
+
+	// Before the run
+	"Pick the repos to run %s on: %d of %d, %s":           "%[1]sを実行するリポジトリを選んでください: %[3]d 個中 %[2]d 個、%[4]s",
+	"Matching %q: %d repos, / to change, esc to clear":    "%q に一致: %d 個のリポジトリ。/ で変更、esc で解除",
+	"Looking for repos... %d found ":                      "リポジトリを探しています... %d 個見つかりました ",
+	"%d repos under %s will run: %s, %s. Proceed? [y/N] ": "%[2]s 以下の %[1]d 個のリポジトリで実行します: %[3]s、%[4]s。続行しますか? [y/N] ",
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aymanbagabas/go-osc52/v2"
+	"github.com/charmbracelet/bubbletea"
+)
+
+// clipboardWrite is an OSC 52 escape sequence drawn at the start of the
+// view, so the terminal copies its text. Drawing it with the rest keeps it
+// from being interleaved with a frame the renderer is writing.
+type clipboardWrite struct {
+	seq string
+	id  int // tells copies apart, so an old one doesn't clear a newer one
+}
+
+// clipboardWritten is sent once a copy was drawn long enough to be in a
+// frame.
+type clipboardWritten struct{ id int }
+
+// clipboardHold is how long a copy stays in the view, a few frames.
+const clipboardHold = 200 * time.Millisecond
+
+// copyResult copies the path of the resultDir to the clipboard, or what went
+// wrong if it failed: the task, the error and the end of its stderr. The
+// terminal does the copying, given an OSC 52 escape sequence, which works
+// over SSH too.
+func (m *model) copyResult() tea.Cmd {
+	dir := m.resultDir()
+	if dir == "" {
+		return nil
+	}
+
+	text, what := dir, tr("the path of %s", dir)
+	for _, f := range slices.Backward(m.failures) {
+		if f.dir == dir {
+			text = strings.Join(append([]string{fmt.Sprintf("%s: %s: %v", f.dir, f.task, f.err)}, f.output...), "\n")
+			what = tr("the error of %s", dir)
+			break
+		}
+	}
+
+	seq := osc52.New(text)
+	if strings.HasPrefix(os.Getenv("TERM"), "screen") {
+		seq = seq.Screen()
+	}
+
+	id := m.clipboard.id + 1
+	m.clipboard = clipboardWrite{seq: seq.String(), id: id}
+	return tea.Batch(
+		tea.Tick(clipboardHold, func(time.Time) tea.Msg { return clipboardWritten{id: id} }),
+		m.println(m.styles.note.Render(tr("Copied %s to the clipboard", what))),
+	)
+}
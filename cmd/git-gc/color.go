@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// colorMode is when output is colored.
+type colorMode string
+
+const (
+	colorAuto   colorMode = "auto"   // on terminals that support it, unless NO_COLOR is set
+	colorAlways colorMode = "always" // even when piped, e.g. into less -R
+	colorNever  colorMode = "never"
+)
+
+var colorModes = []colorMode{colorAuto, colorAlways, colorNever}
+
+func parseColorMode(s string) (colorMode, error) {
+	for _, c := range colorModes {
+		if string(c) == s {
+			return c, nil
+		}
+	}
+
+	return "", fmt.Errorf("unknown color mode %q (available: %v)", s, colorModes)
+}
+
+// apply sets the color profile everything is rendered with. Left to auto,
+// lipgloss asks the terminal, which honors NO_COLOR too.
+func (c colorMode) apply() {
+	switch {
+	case c == colorNever, c == colorAuto && os.Getenv("NO_COLOR") != "":
+		lipgloss.SetColorProfile(termenv.Ascii)
+	case c == colorAlways:
+		lipgloss.SetColorProfile(termenv.ANSI256)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/BurntSushi/toml"
+)
+
+// config is the optional TOML config file. Flags cover per-run choices; the
+// config file holds settings that are tedious to pass on every invocation.
+type config struct {
+	Hooks hooksConfig `toml:"hooks"`
+
+	// Env is set for every process git-gc runs, on top of defaultGitEnv.
+	Env map[string]string `toml:"env"`
+
+	// Theme overrides colors of defaultTheme.
+	Theme theme `toml:"theme"`
+
+	// Open is the command the o key opens a finished repo with, a template
+	// like the hooks'.
+	Open string `toml:"open"`
+}
+
+type hooksConfig struct {
+	Pre  string `toml:"pre"`  // runs in each repo before its tasks
+	Post string `toml:"post"` // runs in each repo after its tasks, even on failure
+}
+
+// defaultConfigPath returns the config file location used when --config
+// isn't given, e.g. ~/.config/git-gc/config.toml.
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(dir, "git-gc", "config.toml")
+}
+
+// loadConfig reads the config file at path. A missing file is only an error
+// when the path was given explicitly.
+func loadConfig(path string) (config, error) {
+	explicit := path != ""
+	if !explicit {
+		path = defaultConfigPath()
+	}
+
+	var cfg config
+	if path == "" {
+		return cfg, nil
+	}
+
+	if _, err := toml.DecodeFile(path, &cfg); err != nil {
+		if !explicit && errors.Is(err, fs.ErrNotExist) {
+			return cfg, nil
+		}
+
+		return cfg, fmt.Errorf("could not load config %s: %w", path, err)
+	}
+
+	return cfg, nil
+}
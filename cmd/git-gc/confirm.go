@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// confirmedElsewhere are the flags the confirmation already spells out, or
+// that don't matter to it.
+var confirmedElsewhere = []string{"root", "tasks", "parallel", "parallel-net", "parallel-disk", "all", "config"}
+
+// confirmRun asks on the terminal whether to run the pipeline on the repos
+// of m under root, with the flags the run was started with, so that a
+// destructive combination of them doesn't run by accident.
+func confirmRun(m model, root string, flags []string) (bool, error) {
+	names := make([]string, len(m.pipeline))
+	for i, t := range m.pipeline {
+		names[i] = t.name
+	}
+
+	// The rest of the flags change what the tasks do
+	var options []string
+	for _, f := range flags {
+		name, _, _ := strings.Cut(strings.TrimPrefix(f, "--"), "=")
+		if !slices.Contains(confirmedElsewhere, name) {
+			options = append(options, f)
+		}
+	}
+
+	what := strings.Join(names, ", ")
+	if len(options) > 0 {
+		what += " (" + strings.Join(options, " ") + ")"
+	}
+
+	fmt.Print(tr("%d repos under %s will run: %s, %s. Proceed? [y/N] ", len(m.directories), root, what, m.parallelism()))
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	}
+
+	return false, nil
+}
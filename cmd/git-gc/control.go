@@ -0,0 +1,265 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/bubbletea"
+)
+
+// controlCommands are what clients can ask a daemon through its control
+// socket, each a subcommand of git-gc.
+var controlCommands = []string{"status", "run-now", "pause", "unpause", "add"}
+
+// controlSocketPath returns where the daemon for root listens, next to its
+// instance lock. Socket paths can't be longer than 104 bytes on macOS, so
+// it's named by a hash of root alone.
+func controlSocketPath(root string) string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+
+	sum := sha256.Sum256([]byte(root))
+	return filepath.Join(dir, "git-gc", "locks", hex.EncodeToString(sum[:8])+".sock")
+}
+
+// pauseSet pauses or unpauses starting new tasks.
+type pauseSet struct {
+	paused bool
+}
+
+// statusRequest asks the model to describe the run in progress.
+type statusRequest struct {
+	reply chan<- string
+}
+
+// daemonControl is the state of a daemon that clients can inspect and change
+// through the control socket.
+type daemonControl struct {
+	mu       sync.Mutex
+	program  *tea.Program // the run in progress, nil between runs
+	scanning bool         // a run is looking for repos, and has no program yet
+	pending  []string     // paths to add to the run once its scan is done
+	paused   bool
+	next     time.Time // when the next run starts
+	last     string    // how the last run went
+	wake     chan struct{}
+}
+
+func newDaemonControl() *daemonControl {
+	return &daemonControl{wake: make(chan struct{}, 1)}
+}
+
+// errDaemonRunning is returned by listen when another daemon serves the
+// control socket of the same root.
+var errDaemonRunning = errors.New("another git-gc daemon is already running")
+
+// listen serves the control socket for root until the returned func closes
+// it. Unix domain sockets are supported on Windows 10 and later too.
+func (c *daemonControl) listen(root string) (func(), error) {
+	path := controlSocketPath(root)
+	if path == "" {
+		return nil, errors.New("could not determine the control socket path")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("could not create socket dir: %w", err)
+	}
+
+	// Runs only hold the instance lock while they're running, so it doesn't
+	// keep two daemons from sharing a root. A socket that still answers
+	// belongs to a daemon that's alive; one that doesn't was left behind.
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("%w in %s", errDaemonRunning, root)
+	}
+
+	_ = os.Remove(path)
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			go c.handle(conn)
+		}
+	}()
+
+	return func() { _ = ln.Close() }, nil
+}
+
+func (c *daemonControl) handle(conn net.Conn) {
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return
+	}
+
+	_, _ = io.WriteString(conn, c.do(strings.TrimSuffix(line, "\n"))+"\n")
+}
+
+// do carries out a client's request, a command followed by its argument if
+// it takes one, and returns the reply.
+func (c *daemonControl) do(request string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	command, arg, _ := strings.Cut(request, " ")
+	switch command {
+	case "status":
+		state := tr("idle, next run at %s", c.next.Format(time.DateTime))
+		if c.scanning {
+			state = tr("looking for repos")
+		}
+
+		if c.paused {
+			state = tr("paused, %s", state)
+		}
+
+		// The run describes being paused itself
+		if c.program != nil {
+			reply := make(chan string, 1)
+			c.program.Send(statusRequest{reply: reply})
+			select {
+			case state = <-reply:
+			case <-time.After(5 * time.Second):
+				state = tr("running")
+			}
+		}
+
+		if c.last != "" {
+			state += "\n" + tr("Last run: %s", c.last)
+		}
+
+		return state
+	case "run-now":
+		if c.program != nil || c.scanning {
+			return tr("Already running")
+		}
+
+		select {
+		case c.wake <- struct{}{}:
+		default:
+		}
+
+		return tr("Starting a run")
+	case "add":
+		if c.scanning {
+			c.pending = append(c.pending, arg)
+			return tr("Adding %s to the run once it has found its repos", arg)
+		}
+
+		if c.program == nil {
+			return tr("No run in progress to add %s to", arg)
+		}
+
+		c.program.Send(addRequest{path: arg})
+		return tr("Looking for repos in %s to add to the run", arg)
+	case "pause", "unpause":
+		c.paused = command == "pause"
+		if c.program != nil {
+			c.program.Send(pauseSet{paused: c.paused})
+		}
+
+		if c.paused {
+			return tr("Paused, no new tasks start until unpaused")
+		}
+
+		return tr("Unpaused")
+	default:
+		return tr("Unknown command %q", command)
+	}
+}
+
+// scanStarted records that a run started, and is looking for repos.
+func (c *daemonControl) scanStarted() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.scanning, c.next = true, time.Now()
+}
+
+// startProgram creates the program of a run that's starting with
+// newProgram, which is told whether new tasks are paused, and is sent the
+// paths added while the run was looking for repos once it runs.
+func (c *daemonControl) startProgram(newProgram func(paused bool) *tea.Program) *tea.Program {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p := newProgram(c.paused)
+	pending := c.pending
+	go func() {
+		for _, path := range pending {
+			p.Send(addRequest{path: path})
+		}
+	}()
+
+	c.program, c.scanning, c.pending = p, false, nil
+	return p
+}
+
+// finished records how a run went, and when the next one starts.
+func (c *daemonControl) finished(last string, next time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.program, c.scanning, c.pending = nil, false, nil
+	c.last, c.next = last, next
+}
+
+// addRequests returns the requests adding paths to a daemon's run, which
+// are resolved here since the daemon runs elsewhere.
+func addRequests(paths []string) ([]string, error) {
+	if len(paths) == 0 {
+		return nil, errors.New("no paths given to add")
+	}
+
+	requests := make([]string, len(paths))
+	for i, path := range paths {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return nil, err
+		}
+
+		requests[i] = "add " + abs
+	}
+
+	return requests, nil
+}
+
+// sendControl sends command to the daemon running in root and returns its
+// reply.
+func sendControl(root, command string) (string, error) {
+	conn, err := net.DialTimeout("unix", controlSocketPath(root), 5*time.Second)
+	if err != nil {
+		return "", fmt.Errorf("no git-gc daemon is running in %s", root)
+	}
+	defer conn.Close()
+
+	_ = conn.SetDeadline(time.Now().Add(10 * time.Second))
+	if _, err := io.WriteString(conn, command+"\n"); err != nil {
+		return "", err
+	}
+
+	reply, err := io.ReadAll(conn)
+	return strings.TrimSpace(string(reply)), err
+}
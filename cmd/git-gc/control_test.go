@@ -0,0 +1,46 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// TestDaemonControlScanning checks the replies while a run is looking for
+// repos, before it has a program to send requests to.
+func TestDaemonControlScanning(t *testing.T) {
+	c := newDaemonControl()
+	c.scanStarted()
+
+	if got := c.do("status"); got != "looking for repos" {
+		t.Errorf("status = %q, want %q", got, "looking for repos")
+	}
+
+	if got := c.do("run-now"); got != "Already running" {
+		t.Errorf("run-now = %q, want %q", got, "Already running")
+	}
+
+	select {
+	case <-c.wake:
+		t.Error("run-now queued another run")
+	default:
+	}
+
+	if got := c.do("add /src/new"); !strings.HasPrefix(got, "Adding /src/new") {
+		t.Errorf("add = %q, want it accepted", got)
+	}
+
+	if !slices.Equal(c.pending, []string{"/src/new"}) {
+		t.Errorf("pending = %q, want [/src/new]", c.pending)
+	}
+}
+
+// TestControlSocketPath checks that the socket path doesn't grow with the
+// root, as sun_path only holds 104 bytes on macOS.
+func TestControlSocketPath(t *testing.T) {
+	short := controlSocketPath("/src")
+	long := controlSocketPath("/" + strings.Repeat("very-long-directory-name/", 20))
+	if len(short) != len(long) || short == long {
+		t.Errorf("socket paths %s and %s, want distinct ones of the same length", short, long)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clockTicks is the kernel's USER_HZ, which is 100 on every architecture Go
+// supports.
+const clockTicks = 100
+
+// groupCPUTime returns the CPU time used so far by the processes of the
+// process group pgid.
+func groupCPUTime(pgid int) (time.Duration, bool) {
+	stats, err := filepath.Glob("/proc/[0-9]*/stat")
+	if err != nil {
+		return 0, false
+	}
+
+	var ticks int64
+	for _, path := range stats {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+
+		// The command name is in parentheses and may contain spaces
+		i := strings.LastIndexByte(string(b), ')')
+		if i < 0 {
+			continue
+		}
+
+		// state ppid pgrp ... utime stime are the 3rd to 15th fields
+		fields := strings.Fields(string(b[i+1:]))
+		if len(fields) < 13 || fields[2] != strconv.Itoa(pgid) {
+			continue
+		}
+
+		utime, _ := strconv.ParseInt(fields[11], 10, 64)
+		stime, _ := strconv.ParseInt(fields[12], 10, 64)
+		ticks += utime + stime
+	}
+
+	return time.Duration(ticks) * time.Second / clockTicks, true
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/charmbracelet/bubbletea"
+)
+
+// runDaemon stays resident, running every interval without a UI and logging
+// a summary of each run, until it's interrupted. Each run looks for repos
+// again, so new ones are picked up. With once, it exits after the first run
+// with its exit code instead, for schedulers that start it.
+func runDaemon(opts runOptions, every time.Duration, once bool) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	logger := log.New(os.Stderr, "", log.LstdFlags)
+	opts.logf = logger.Printf
+	opts.unattended = true
+	opts.control = newDaemonControl()
+
+	closeSocket, err := opts.control.listen(opts.root)
+	if errors.Is(err, errDaemonRunning) {
+		logger.Printf("Error %s", err)
+		return exitLocked
+	}
+
+	if err != nil {
+		logger.Printf("Error listening on the control socket: %s", err)
+		return exitError
+	}
+	defer closeSocket()
+
+	if once {
+		logger.Printf("Running once in %s", opts.root)
+	} else {
+		logger.Printf("Running every %s in %s", every, opts.root)
+	}
+
+	for {
+		start := time.Now()
+		final, err := run(opts, tea.WithInput(nil), tea.WithoutRenderer())
+
+		var (
+			running *instanceRunningError
+			last    string
+			code    int
+		)
+		switch {
+		case errors.As(err, &running):
+			last, code = fmt.Sprintf("skipped, %s", running), exitLocked
+			logger.Printf("Skipping this run, %s", running)
+		case err != nil:
+			last, code = "error "+err.Error(), exitError
+			logger.Printf("Error %s", err)
+		default:
+			last, code = logRun(logger, final, time.Since(start)), final.exitCode()
+		}
+
+		if once {
+			return code
+		}
+
+		// A run cut short by a signal exits like an interrupted one-shot run,
+		// so that service managers can tell it from a clean stop
+		if ctx.Err() != nil {
+			logger.Printf("Stopped")
+			return code
+		}
+
+		next := start.Add(every)
+		opts.control.finished(last, next)
+		logger.Printf("Next run at %s", next.Format(time.DateTime))
+		select {
+		case <-ctx.Done():
+			logger.Printf("Stopped")
+			return exitOK
+		case <-opts.control.wake:
+			logger.Printf("Running now as asked")
+		case <-time.After(time.Until(next)):
+		}
+	}
+}
+
+// runSummary describes how a run went in one line.
+func runSummary(m model, took time.Duration) string {
+	return tr(
+		"%s on %d of %d repos in %s, %d failed, %d skipped, %d left for the next run",
+		m.action(), m.index-len(m.skipped), len(m.directories), took.Round(time.Second),
+		len(m.failures), len(m.skipped), len(m.remaining),
+	)
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// detailLines is how many lines of git output the detail pane shows.
+const detailLines = 8
+
+// outputTail keeps the last lines a repo's commands wrote to stderr for the
+// detail pane, along with the line they're writing, which is where git's
+// progress meters are.
+type outputTail struct {
+	mu      sync.Mutex
+	lines   []string
+	partial []byte
+}
+
+func (t *outputTail) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.partial = append(t.partial, p...)
+	for {
+		i := bytes.IndexByte(t.partial, '\n')
+		if i < 0 {
+			break
+		}
+
+		if line := lastSegment(t.partial[:i]); line != "" {
+			t.lines = append(t.lines, line)
+		}
+
+		t.partial = t.partial[i+1:]
+	}
+
+	if len(t.lines) > detailLines {
+		t.lines = slices.Clone(t.lines[len(t.lines)-detailLines:])
+	}
+
+	// Only the last state of a redrawn line is worth keeping
+	if i := bytes.LastIndexByte(bytes.TrimRight(t.partial, "\r"), '\r'); i >= 0 {
+		t.partial = slices.Clone(t.partial[i+1:])
+	}
+
+	return len(p), nil
+}
+
+// recent returns the last lines, including the one being written.
+func (t *outputTail) recent() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	lines := slices.Clone(t.lines)
+	if line := lastSegment(t.partial); line != "" {
+		lines = append(lines, line)
+	}
+
+	return lines[max(0, len(lines)-detailLines):]
+}
+
+// lastSegment returns the last non-empty state of a line redrawn with
+// carriage returns.
+func lastSegment(line []byte) string {
+	for _, segment := range slices.Backward(bytes.Split(line, []byte("\r"))) {
+		if s := strings.TrimSpace(string(segment)); s != "" {
+			return s
+		}
+	}
+
+	return ""
+}
+
+// tail returns the output tail of dir's commands.
+func (r *runner) tail(dir string) *outputTail {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, ok := r.tails[dir]
+	if !ok {
+		t = &outputTail{}
+		r.tails[dir] = t
+	}
+
+	return t
+}
+
+// forget drops the output tail of a repo that's done.
+func (r *runner) forget(dir string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.tails, dir)
+}
+
+// inFlight returns the repos that started their first task and aren't done
+// yet, oldest first.
+func (m model) inFlight() []string {
+	return slices.SortedFunc(maps.Keys(m.started), func(a, b string) int {
+		return cmp.Or(m.started[a].Compare(m.started[b]), strings.Compare(a, b))
+	})
+}
+
+// detailDir is the repo the detail pane shows: the selected one while it's
+// in flight, or else the oldest one.
+func (m model) detailDir() string {
+	if _, ok := m.started[m.selected]; ok {
+		return m.selected
+	}
+
+	if dirs := m.inFlight(); len(dirs) > 0 {
+		return dirs[0]
+	}
+
+	return ""
+}
+
+// selectNext selects the in-flight repo after the one the detail pane
+// shows, or before it if step is -1.
+func (m *model) selectNext(step int) {
+	dirs := m.inFlight()
+	if len(dirs) == 0 {
+		return
+	}
+
+	i := slices.Index(dirs, m.detailDir())
+	m.selected = dirs[(i+step+len(dirs))%len(dirs)]
+}
+
+// skipRepo skips dir, killing its running task. A repo waiting for its next
+// task or an answer is skipped right away.
+func (m *model) skipRepo(dir string) tea.Cmd {
+	const reason = "skipped by the user"
+	if m.runner.skip(dir, reason) {
+		return nil
+	}
+
+	waiting := slices.ContainsFunc(m.confirms, func(c confirmRequest) bool { return c.job.dir == dir })
+	m.confirms = slices.DeleteFunc(m.confirms, func(c confirmRequest) bool { return c.job.dir == dir })
+	for k := range m.pools {
+		p := &m.pools[k]
+		waiting = waiting || slices.ContainsFunc(p.queue, func(j job) bool { return j.dir == dir })
+		p.queue = slices.DeleteFunc(p.queue, func(j job) bool { return j.dir == dir })
+	}
+
+	// Otherwise it's running a hook or about to report back
+	if !waiting {
+		return nil
+	}
+
+	m.skipped = append(m.skipped, repoSkip{dir: dir, reason: reason})
+	return m.finishRepo(dir, statusSkipped)
+}
+
+// detailPane renders the box tailing the git output of the selected repo.
+func (m model) detailPane() string {
+	dir := m.detailDir()
+	if !m.detail || dir == "" {
+		return ""
+	}
+
+	lines := m.runner.tail(dir).recent()
+	if len(lines) == 0 {
+		lines = []string{m.styles.note.Render("Nothing yet")}
+	}
+
+	width := max(0, m.width-4) // the border and padding
+	cut := lipgloss.NewStyle().MaxWidth(width)
+	for i, line := range lines {
+		lines[i] = cut.Render(line)
+	}
+
+	title := m.styles.note.Render(fmt.Sprintf("%s (tab for the next repo, d to close)", dir))
+	box := m.styles.border.
+		Width(width + 2).
+		Height(detailLines)
+
+	return cut.Render(title) + "\n" + box.Render(strings.Join(lines, "\n")) + "\n"
+}
@@ -0,0 +1,19 @@
+//go:build unix
+
+package main
+
+import (
+	"strconv"
+	"syscall"
+)
+
+// deviceID identifies the device path is stored on.
+func deviceID(path string) (string, error) {
+	var st syscall.Stat_t
+	if err := syscall.Stat(path, &st); err != nil {
+		return "", err
+	}
+
+	// Dev is an int32 on some platforms
+	return strconv.FormatUint(uint64(st.Dev), 10), nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// deviceLimits caps how many disk-bound tasks run at once on repos stored on
+// the same device, so a slow disk isn't thrashed by parallel repacks while
+// fast ones get the whole disk pool.
+type deviceLimits struct {
+	limits   map[string]int // by device ID
+	inFlight map[string]int
+	devices  map[string]string // device ID of each repo seen so far
+}
+
+// parseDeviceLimits parses a comma separated list of path=limit pairs, e.g.
+// "/=8,/Volumes/External=1". Each path stands for the device it's stored on.
+func parseDeviceLimits(list string) (deviceLimits, error) {
+	d := deviceLimits{
+		limits:   make(map[string]int),
+		inFlight: make(map[string]int),
+		devices:  make(map[string]string),
+	}
+
+	for _, pair := range strings.Split(list, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		path, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return deviceLimits{}, fmt.Errorf("invalid device limit %q, expected path=limit", pair)
+		}
+
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			return deviceLimits{}, fmt.Errorf("invalid limit in %q, expected a positive number", pair)
+		}
+
+		dev, err := deviceID(path)
+		if err != nil {
+			return deviceLimits{}, fmt.Errorf("could not find the device of %s: %w", path, err)
+		}
+
+		d.limits[dev] = limit
+	}
+
+	return d, nil
+}
+
+// device returns the device ID of repo, or "" if it has no limit.
+func (d deviceLimits) device(repo string) string {
+	if len(d.limits) == 0 {
+		return ""
+	}
+
+	dev, ok := d.devices[repo]
+	if !ok {
+		// A repo that can't be stat'ed fails soon enough on its own
+		dev, _ = deviceID(repo)
+		if _, limited := d.limits[dev]; !limited {
+			dev = ""
+		}
+
+		d.devices[repo] = dev
+	}
+
+	return dev
+}
+
+// hasRoom reports whether another task can start on repo's device.
+func (d deviceLimits) hasRoom(repo string) bool {
+	dev := d.device(repo)
+	return dev == "" || d.inFlight[dev] < d.limits[dev]
+}
+
+func (d deviceLimits) acquire(repo string) {
+	if dev := d.device(repo); dev != "" {
+		d.inFlight[dev]++
+	}
+}
+
+func (d deviceLimits) release(repo string) {
+	if dev := d.device(repo); dev != "" {
+		d.inFlight[dev]--
+	}
+}
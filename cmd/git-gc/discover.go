@@ -0,0 +1,157 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// scanProgress is where a scan is at, for showing it while it runs.
+type scanProgress struct {
+	mu    sync.Mutex
+	dir   string // the directory being listed
+	found int
+
+	onFound func(dir string) // called with each repo found, if set
+}
+
+func (p *scanProgress) listing(dir string) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.dir = dir
+}
+
+func (p *scanProgress) foundRepo(dir string) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.found++
+	if p.onFound != nil {
+		p.onFound(dir)
+	}
+}
+
+func (p *scanProgress) get() (string, int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.dir, p.found
+}
+
+// scanScreen is the screen shown while looking for repos, with how many were
+// found and the directory being listed, which tells a scan stuck in a huge
+// cache directory apart from a slow one.
+type scanScreen struct {
+	root     string
+	opts     scanOptions
+	progress *scanProgress
+	spinner  spinner.Model
+	width    int
+	note     lipgloss.Style
+
+	scan      scanResult
+	err       error
+	done      bool
+	cancelled bool
+}
+
+// scanDone is sent once the scan is over.
+type scanDone struct {
+	scan scanResult
+	err  error
+}
+
+// scanRefresh redraws the screen with where the scan is at.
+type scanRefresh struct{}
+
+func refreshScan() tea.Cmd {
+	return tea.Tick(100*time.Millisecond, func(time.Time) tea.Msg { return scanRefresh{} })
+}
+
+// scanShowing finds the repos under root like findDirectories, showing how
+// it's going. It reports false if the user quit instead.
+func scanShowing(root string, opts scanOptions, st styles) (scanResult, bool, error) {
+	s := scanScreen{root: root, opts: opts, progress: &scanProgress{}, spinner: spinner.New(spinner.WithSpinner(spinner.Dot)), note: st.note}
+	s.spinner.Style = st.start
+	s.opts.progress = s.progress
+
+	final, err := tea.NewProgram(s).Run()
+	if err != nil {
+		return scanResult{}, false, err
+	}
+
+	s = final.(scanScreen)
+	return s.scan, !s.cancelled, s.err
+}
+
+func (s scanScreen) Init() tea.Cmd {
+	scan := func() tea.Msg {
+		result, err := findDirectories(s.root, s.opts)
+		return scanDone{scan: result, err: err}
+	}
+
+	return tea.Batch(scan, s.spinner.Tick, refreshScan())
+}
+
+func (s scanScreen) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		s.width = msg.Width
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c", "q", "esc":
+			s.cancelled, s.done = true, true
+			return s, tea.Quit
+		}
+	case scanDone:
+		s.scan, s.err, s.done = msg.scan, msg.err, true
+		return s, tea.Quit
+	case scanRefresh:
+		return s, refreshScan()
+	case spinner.TickMsg:
+		var cmd tea.Cmd
+		s.spinner, cmd = s.spinner.Update(msg)
+		return s, cmd
+	}
+
+	return s, nil
+}
+
+func (s scanScreen) View() string {
+	// Nothing is left behind once the run's UI takes over
+	if s.done {
+		return ""
+	}
+
+	dir, found := s.progress.get()
+	status := s.spinner.View() + " " + tr("Looking for repos... %d found ", found)
+	return status + s.note.Render(truncateLeft(dir, s.width-lipgloss.Width(status)))
+}
+
+// truncateLeft cuts the start of path off to fit in width, where the end of
+// it tells more.
+func truncateLeft(path string, width int) string {
+	if lipgloss.Width(path) <= width {
+		return path
+	}
+
+	if width <= 1 {
+		return ""
+	}
+
+	runes := []rune(path)
+	for len(runes) > 0 && lipgloss.Width(string(runes))+1 > width {
+		runes = runes[1:]
+	}
+
+	return "…" + string(runes)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+)
+
+// printDryRun prints the repos a run would process, in order, and what each
+// of their tasks and hooks would run, for --dry-run. Deciding that only
+// reads the repos; the checks right before a repo's first task, such as
+// the one for free space, aren't made.
+func printDryRun(w io.Writer, dirs []string, pipeline []task, h hooks) {
+	_, _ = fmt.Fprintf(w, "Would process %d repos:\n", len(dirs))
+	for _, dir := range dirs {
+		_, _ = fmt.Fprintln(w, dir)
+		printHook(w, h.pre, dir)
+		for _, t := range pipeline {
+			inv, err := t.command(context.Background(), dir)
+			switch {
+			case err != nil:
+				_, _ = fmt.Fprintf(w, "  %s: %s\n", t.name, err)
+			case len(inv.argv) == 0:
+				_, _ = fmt.Fprintf(w, "  %s: nothing to do%s\n", t.name, dryRunNotes(inv))
+			default:
+				_, _ = fmt.Fprintf(w, "  %s%s\n", quoteArgv(inv.argv), dryRunNotes(inv))
+			}
+		}
+		printHook(w, h.post, dir)
+	}
+}
+
+func printHook(w io.Writer, tmpl *template.Template, dir string) {
+	if tmpl == nil {
+		return
+	}
+
+	command, _, err := renderHook(tmpl, dir, hookData{Result: "success"})
+	if err != nil {
+		_, _ = fmt.Fprintf(w, "  %s\n", err)
+		return
+	}
+
+	_, _ = fmt.Fprintf(w, "  %s hook: %s\n", tmpl.Name(), command)
+}
+
+// dryRunNotes says why a command was chosen, and whether it asks first.
+func dryRunNotes(inv invocation) string {
+	var notes []string
+	if inv.note != "" {
+		notes = append(notes, inv.note)
+	}
+
+	if inv.confirm != "" {
+		notes = append(notes, "asks first")
+	}
+
+	if len(notes) == 0 {
+		return ""
+	}
+
+	return " (" + strings.Join(notes, "; ") + ")"
+}
+
+// quoteArgv joins argv into a command line, quoting the arguments the shell
+// would split or expand.
+func quoteArgv(argv []string) string {
+	quoted := make([]string, len(argv))
+	for i, arg := range argv {
+		quoted[i] = arg
+		if arg == "" || strings.ContainsAny(arg, " \t\n'\"\\$`*?[]{}()<>|&;#~") {
+			quoted[i] = shellQuote(arg)
+		}
+	}
+
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbletea"
+)
+
+// addRequest asks the model to look for repos in path, another root or a
+// single repo, and add them to the run.
+type addRequest struct {
+	path string
+}
+
+// reposFound is sent once the repos of an addRequest were found.
+type reposFound struct {
+	path string
+	scan scanResult
+	err  error
+}
+
+// findAdded looks for the repos in path in the background.
+func (m model) findAdded(path string) tea.Cmd {
+	opts := m.scan
+	return func() tea.Msg {
+		scan, err := findDirectories(expandHome(path), opts)
+		return reposFound{path: path, scan: scan, err: err}
+	}
+}
+
+// addRepos queues the repos found in msg that aren't part of the run yet,
+// and returns the line to print about it.
+func (m *model) addRepos(msg reposFound) tea.Cmd {
+	if msg.err != nil {
+		return m.println(m.styles.note.Render(fmt.Sprintf("Could not add %s: %s", msg.path, msg.err)))
+	}
+
+	known := make(map[string]bool, len(m.directories))
+	for _, dir := range m.directories {
+		known[dir] = true
+	}
+
+	var added int
+	for _, dir := range msg.scan.dirs {
+		if !known[dir] {
+			m.directories = append(m.directories, dir)
+			m.enqueue(job{dir: dir})
+			added++
+		}
+	}
+
+	m.dispatch()
+	return tea.Batch(
+		m.progress.SetPercent(m.percent()),
+		m.windowTitle(),
+		m.println(m.styles.note.Render(fmt.Sprintf("Added %d repos from %s", added, msg.path))),
+	)
+}
+
+// typePath edits the path the user is typing after pressing a, and looks
+// for repos in it once they press enter.
+func (m model) typePath(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEnter:
+		path := strings.TrimSpace(m.addInput)
+		m.adding, m.addInput = false, ""
+		if path != "" {
+			return m, m.findAdded(path)
+		}
+	case tea.KeyEsc, tea.KeyCtrlC:
+		m.adding, m.addInput = false, ""
+	default:
+		m.addInput = editLine(m.addInput, msg)
+	}
+
+	return m, nil
+}
+
+// expandHome expands a leading ~ to the user's home directory, which the
+// shell doesn't do for paths typed into the UI.
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") && !strings.HasPrefix(path, `~`+string(filepath.Separator)) {
+		return path
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+
+	return filepath.Join(home, path[1:])
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// defaultGitEnv keeps the processes git-gc spawns from waiting on credential
+// prompts nobody will answer, and from fighting editors and IDEs over
+// optional locks such as the index refresh of `git status`. An empty
+// GIT_ASKPASS is how git is told there's no askpass program, on every
+// platform, rather than falling back to core.askPass or SSH_ASKPASS.
+var defaultGitEnv = map[string]string{
+	"GIT_TERMINAL_PROMPT": "0",
+	"GIT_ASKPASS":         "",
+	"GIT_OPTIONAL_LOCKS":  "0",
+}
+
+// batchSSH makes ssh fail instead of asking for a passphrase or whether to
+// trust a host key on the terminal, which git's prompt settings don't cover.
+const batchSSH = "ssh -o BatchMode=yes"
+
+// applyGitEnv sets defaultGitEnv, and GIT_SSH_COMMAND unless the user chose
+// how git runs ssh, with overrides from the config file on top, in git-gc's
+// own environment so every child process inherits it. An empty override
+// leaves the variable as it was.
+func applyGitEnv(overrides map[string]string) error {
+	env := maps.Clone(defaultGitEnv)
+	if !sshConfigured() {
+		env["GIT_SSH_COMMAND"] = batchSSH
+	}
+
+	for k, v := range overrides {
+		if v == "" {
+			delete(env, k)
+			continue
+		}
+
+		env[k] = v
+	}
+
+	for k, v := range env {
+		if err := os.Setenv(k, v); err != nil {
+			return fmt.Errorf("could not set %s: %w", k, err)
+		}
+	}
+
+	return nil
+}
+
+// sshConfigured reports whether the user set how git runs ssh, with
+// GIT_SSH_COMMAND or GIT_SSH, or core.sshCommand in their global or system
+// git config, which GIT_SSH_COMMAND would take precedence over.
+func sshConfigured() bool {
+	if os.Getenv("GIT_SSH_COMMAND") != "" || os.Getenv("GIT_SSH") != "" {
+		return true
+	}
+
+	for _, scope := range []string{"--global", "--system"} {
+		if out, err := exec.Command(gitPath, "config", scope, "--get", "core.sshCommand").Output(); err == nil && strings.TrimSpace(string(out)) != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// packMemoryConfig returns the git config that keeps a single pack-objects
+// within about limit bytes: half of it for the delta windows of its threads,
+// a quarter for the delta cache, and the rest for everything else. It uses a
+// thread per 256 MiB, so small limits don't leave each thread a uselessly
+// small window.
+func packMemoryConfig(limit int64) map[string]string {
+	threads := min(int64(runtime.NumCPU()), max(1, limit/(256<<20)))
+	return map[string]string{
+		"pack.threads":        fmt.Sprint(threads),
+		"pack.windowMemory":   fmt.Sprint(limit / 2 / threads),
+		"pack.deltaCacheSize": fmt.Sprint(limit / 4),
+	}
+}
+
+// applyGitConfig passes config to every git that git-gc's children run, as
+// if it was given with `git -c`, on top of any such config git-gc was
+// itself started with.
+func applyGitConfig(config map[string]string) error {
+	params := []string{os.Getenv("GIT_CONFIG_PARAMETERS")}
+	for k, v := range config {
+		// Each parameter is single quoted, the way git itself quotes them
+		params = append(params, shellQuote(k+"="+v))
+	}
+
+	return os.Setenv("GIT_CONFIG_PARAMETERS", strings.TrimSpace(strings.Join(params, " ")))
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,77 @@
+package main
+
+import "time"
+
+// eta estimates how long the repos that aren't done yet will take, from how
+// long each took the last time. Repos that didn't run before are estimated
+// from their size, at the rate the others took, or else as the average one.
+// It's false until there's anything to go by.
+func (m model) eta() (time.Duration, bool) {
+	// Repos done in this run tell more about this machine than past runs
+	known := make(map[string]time.Duration, len(m.past)+len(m.took))
+	for dir, d := range m.past {
+		known[dir] = d
+	}
+	for dir, d := range m.took {
+		known[dir] = d
+	}
+
+	if len(known) == 0 {
+		return 0, false
+	}
+
+	var sum, weighed time.Duration
+	var weight int64
+	for dir, d := range known {
+		sum += d
+		if w, ok := m.weights[dir]; ok {
+			weighed += d
+			weight += w
+		}
+	}
+	average := sum / time.Duration(len(known))
+
+	var left time.Duration
+	var pending int
+	for _, dir := range m.directories {
+		if m.finished[dir] {
+			continue
+		}
+
+		pending++
+		d, ok := m.past[dir]
+		if !ok {
+			d = average
+			if w, measured := m.weights[dir]; measured && weight > 0 {
+				d = time.Duration(float64(weighed) * float64(w) / float64(weight))
+			}
+		}
+
+		if start, ok := m.started[dir]; ok {
+			d = max(0, d-time.Since(start))
+		}
+
+		left += d
+	}
+
+	if pending == 0 {
+		return 0, false
+	}
+
+	return left / time.Duration(max(1, min(pending, m.pools[kindDisk].limit))), true
+}
+
+// throughput says how long the run has been going and how many repos it
+// finishes per minute, false before the first task started.
+func (m model) throughput() (time.Duration, float64, bool) {
+	elapsed := time.Since(m.startAt)
+	if m.done {
+		elapsed = m.lastDone.Sub(m.startAt)
+	}
+
+	if elapsed <= 0 {
+		return 0, 0, false
+	}
+
+	return elapsed, float64(m.index) / elapsed.Minutes(), true
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// repoFingerprint summarizes a repo's HEAD and objects. When it's the same
+// as after the last successful run, nothing happened that would give gc
+// anything to do.
+func repoFingerprint(ctx context.Context, dir string) (string, error) {
+	// An empty repo has no HEAD commit yet
+	head := "none"
+	if out, err := gitOutput(ctx, dir, "rev-parse", "--verify", "--quiet", "HEAD"); err == nil {
+		head = strings.TrimSpace(string(out))
+	}
+
+	out, err := gitOutput(ctx, dir, "count-objects", "-v")
+	if err != nil {
+		return "", fmt.Errorf("could not count objects: %w", err)
+	}
+
+	counts := make(map[string]string)
+	for _, line := range strings.Split(string(out), "\n") {
+		if key, value, ok := strings.Cut(line, ": "); ok {
+			counts[key] = value
+		}
+	}
+
+	return fmt.Sprintf("%s loose=%s packed=%s packs=%s", head, counts["count"], counts["in-pack"], counts["packs"]), nil
+}
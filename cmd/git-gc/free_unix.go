@@ -0,0 +1,17 @@
+//go:build linux || darwin || freebsd
+
+package main
+
+import "syscall"
+
+// diskFree returns how many bytes are available to unprivileged users on the
+// filesystem path is stored on.
+func diskFree(path string) (int64, bool) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, false
+	}
+
+	// The field types differ between platforms
+	return int64(st.Bavail) * int64(st.Bsize), true
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var procGetDiskFreeSpaceEx = kernel32.NewProc("GetDiskFreeSpaceExW")
+
+// diskFree returns how many bytes are available to the user on the volume
+// path is stored on.
+func diskFree(path string) (int64, bool) {
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, false
+	}
+
+	var avail uint64
+	if ok, _, _ := procGetDiskFreeSpaceEx.Call(uintptr(unsafe.Pointer(p)), uintptr(unsafe.Pointer(&avail)), 0, 0); ok == 0 {
+		return 0, false
+	}
+
+	return int64(avail), true
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/bubbletea"
+)
+
+// fuzzyScore reports whether the characters of pattern appear in s in
+// order, ignoring case, and how well they match: like in fzf, runs of
+// consecutive characters and characters starting a path element count more.
+func fuzzyScore(pattern, s string) (int, bool) {
+	var score int
+	var prev rune
+	var consecutive bool // the previous character matched too
+	rest := []rune(strings.ToLower(pattern))
+	for _, r := range strings.ToLower(s) {
+		if len(rest) == 0 {
+			break
+		}
+
+		matched := r == rest[0]
+		if matched {
+			score++
+			if consecutive {
+				score += 2
+			}
+
+			if prev == 0 || strings.ContainsRune("/\\-_. ", prev) {
+				score += 3
+			}
+
+			rest = rest[1:]
+		}
+
+		consecutive, prev = matched, r
+	}
+
+	return score, len(rest) == 0
+}
+
+// editLine applies a key pressed while typing a line, such as a path or a
+// filter, to it.
+func editLine(line string, msg tea.KeyMsg) string {
+	switch msg.Type {
+	case tea.KeyBackspace:
+		if _, size := utf8.DecodeLastRuneInString(line); size > 0 {
+			return line[:len(line)-size]
+		}
+	case tea.KeySpace:
+		return line + " "
+	case tea.KeyRunes:
+		return line + strings.Map(func(r rune) rune {
+			if unicode.IsControl(r) {
+				return -1
+			}
+
+			return r
+		}, string(msg.Runes))
+	}
+
+	return line
+}
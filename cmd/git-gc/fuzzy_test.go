@@ -0,0 +1,49 @@
+package main
+
+import "testing"
+
+func TestFuzzyScoreMatches(t *testing.T) {
+	for _, tt := range []struct{ pattern, s string }{
+		{"", "src/app"},
+		{"abc", "abc"},
+		{"ABC", "xabc"},
+		{"ac", "abc"},
+		{"gigc", "work/git-gc"},
+	} {
+		if _, ok := fuzzyScore(tt.pattern, tt.s); !ok {
+			t.Errorf("%q doesn't match %q", tt.pattern, tt.s)
+		}
+	}
+
+	for _, tt := range []struct{ pattern, s string }{
+		{"ba", "abc"},
+		{"abcd", "abc"},
+		{"x", ""},
+	} {
+		if _, ok := fuzzyScore(tt.pattern, tt.s); ok {
+			t.Errorf("%q matches %q", tt.pattern, tt.s)
+		}
+	}
+}
+
+// TestFuzzyScoreRanking checks that the first of each pair is listed above
+// the second.
+func TestFuzzyScoreRanking(t *testing.T) {
+	tests := []struct {
+		pattern       string
+		better, worse string
+	}{
+		{"app", "webapp", "wrap-up-prep"},    // a run beats scattered characters
+		{"gc", "work/gc", "work/magic"},      // starting a path element counts
+		{"lib", "lib/x", "xlib/x"},           // so does starting the path
+		{"api", "go/api-server", "go/rapid"}, // or after a dash or dot
+	}
+
+	for _, tt := range tests {
+		better, _ := fuzzyScore(tt.pattern, tt.better)
+		worse, _ := fuzzyScore(tt.pattern, tt.worse)
+		if better <= worse {
+			t.Errorf("%q scores %d in %q and %d in %q, want the first higher", tt.pattern, better, tt.better, worse, tt.worse)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// gitPath is the git executable every git command runs, chosen with --git.
+var gitPath = "git"
+
+// minGitVersion is the oldest git that has everything git-gc relies on, the
+// newest being gc --keep-largest-pack and gc.bigPackThreshold from 2.17.
+var minGitVersion = gitVersion{2, 17, 0}
+
+// gitVersion is a git release as major, minor and patch numbers.
+type gitVersion [3]int
+
+func (v gitVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v[0], v[1], v[2])
+}
+
+func (v gitVersion) less(o gitVersion) bool {
+	for i := range v {
+		if v[i] != o[i] {
+			return v[i] < o[i]
+		}
+	}
+
+	return false
+}
+
+// parseGitVersion parses the output of `git version`, e.g. "git version
+// 2.39.3 (Apple Git-146)" or "git version 2.45.1.windows.1".
+func parseGitVersion(s string) (gitVersion, error) {
+	fields := strings.Fields(s)
+	if len(fields) < 3 || fields[0] != "git" || fields[1] != "version" {
+		return gitVersion{}, fmt.Errorf("unexpected git version %q", strings.TrimSpace(s))
+	}
+
+	var v gitVersion
+	for i, part := range strings.SplitN(fields[2], ".", 4) {
+		if i == len(v) {
+			break
+		}
+
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			// Release candidates look like 2.46.0-rc1 or 2.46.0.rc1
+			n, err = strconv.Atoi(strings.SplitN(part, "-", 2)[0])
+			if err != nil {
+				break
+			}
+		}
+
+		v[i] = n
+	}
+
+	return v, nil
+}
+
+// gitOutput runs git with args in the repo at dir, stopped by ctx like
+// groupCommand's commands, and returns what it wrote to stdout.
+func gitOutput(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	cmd, exited := groupCommand(ctx, dir, append([]string{gitPath, "-C", dir}, args...)...)
+	out, err := cmd.Output()
+	exited()
+	if err != nil && ctx.Err() != nil {
+		return out, contextError(ctx)
+	}
+
+	return out, err
+}
+
+// setGitPath resolves path to a git executable, makes sure it's at least
+// minGitVersion, and makes every git command run it.
+func setGitPath(path string) error {
+	resolved, err := exec.LookPath(path)
+	if err != nil {
+		return fmt.Errorf("could not find git: %w", err)
+	}
+
+	out, err := exec.Command(resolved, "version").Output()
+	if err != nil {
+		return fmt.Errorf("could not run %s: %w", resolved, err)
+	}
+
+	v, err := parseGitVersion(string(out))
+	if err != nil {
+		return err
+	}
+
+	if v.less(minGitVersion) {
+		return fmt.Errorf("%s is git %s, but git-gc needs %s or newer (pick another one with --git)", resolved, v, minGitVersion)
+	}
+
+	gitPath = resolved
+	return nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import "testing"
+
+func TestParseGitVersion(t *testing.T) {
+	tests := []struct {
+		out  string
+		want gitVersion
+	}{
+		{"git version 2.43.0\n", gitVersion{2, 43, 0}},
+		{"git version 2.39.3 (Apple Git-146)", gitVersion{2, 39, 3}},
+		{"git version 2.45.1.windows.1", gitVersion{2, 45, 1}},
+		{"git version 2.46.0-rc1", gitVersion{2, 46, 0}},
+		{"git version 2.46.0.rc1", gitVersion{2, 46, 0}},
+		{"git version 2.17", gitVersion{2, 17, 0}},
+	}
+
+	for _, tt := range tests {
+		got, err := parseGitVersion(tt.out)
+		if err != nil || got != tt.want {
+			t.Errorf("parseGitVersion(%q) = %s, %v, want %s", tt.out, got, err, tt.want)
+		}
+	}
+
+	for _, out := range []string{"", "git version", "hub version 2.14.2"} {
+		if _, err := parseGitVersion(out); err == nil {
+			t.Errorf("parseGitVersion(%q) succeeded, want an error", out)
+		}
+	}
+}
+
+func TestMinGitVersion(t *testing.T) {
+	for _, out := range []string{"git version 2.16.6", "git version 1.99.9", "git version 2.9.5"} {
+		if v, _ := parseGitVersion(out); !v.less(minGitVersion) {
+			t.Errorf("%s is accepted, want it refused as older than %s", v, minGitVersion)
+		}
+	}
+
+	for _, out := range []string{"git version 2.17.0", "git version 2.17.1.windows.1", "git version 3.0.0"} {
+		if v, _ := parseGitVersion(out); v.less(minGitVersion) {
+			t.Errorf("%s is refused as older than %s", v, minGitVersion)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// groupedHistory renders the lines of the history about repos under their
+// parent directories, with how many of each directory's repos are done.
+// Lines about no repo are left out.
+func (m model) groupedHistory() []string {
+	groups := make(map[string][]historyLine)
+	for _, line := range m.sortedHistory() {
+		if line.dir == "" {
+			continue
+		}
+
+		if _, ok := fuzzyScore(m.filter, line.dir); !ok && m.filter != "" {
+			continue
+		}
+
+		parent := filepath.Dir(line.dir)
+		groups[parent] = append(groups[parent], line)
+	}
+
+	total := make(map[string]int)
+	done := make(map[string]int)
+	for _, dir := range m.directories {
+		parent := filepath.Dir(dir)
+		total[parent]++
+		if m.finished[dir] {
+			done[parent]++
+		}
+	}
+
+	failed := make(map[string]int)
+	for _, f := range m.failures {
+		failed[filepath.Dir(f.dir)]++
+	}
+
+	var lines []string
+	for _, parent := range slices.Sorted(maps.Keys(groups)) {
+		counts := tr("%d/%d done", done[parent], total[parent])
+		if n := failed[parent]; n > 0 {
+			counts += tr(", %d failed", n)
+		}
+
+		lines = append(lines, m.styles.currentDirName.Render(parent)+" "+m.styles.note.Render(counts))
+		for _, line := range groups[parent] {
+			lines = append(lines, "  "+strings.Replace(line.text, line.dir, filepath.Base(line.dir), 1))
+		}
+	}
+
+	return lines
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// keyMap lists the keys of the UI for the help overlay.
+type keyMap struct {
+	scroll, filter, group, sort, selectResult, open, copy, selectRepo, detail, skip, kill key.Binding
+	parallel, pause, add, confirm, fullScreen, help, quit                                 key.Binding
+}
+
+var keys = keyMap{
+	scroll:       key.NewBinding(key.WithKeys("pgup", "pgdown", "home", "end"), key.WithHelp("pgup/pgdn/home/end", "scroll the finished repos")),
+	filter:       key.NewBinding(key.WithKeys("/"), key.WithHelp("/", "filter the finished repos")),
+	group:        key.NewBinding(key.WithKeys("g"), key.WithHelp("g", "group the finished repos by directory")),
+	sort:         key.NewBinding(key.WithKeys("S"), key.WithHelp("S", "sort them by duration, space freed or status")),
+	selectResult: key.NewBinding(key.WithKeys("up", "down"), key.WithHelp("↑/↓", "select a finished repo")),
+	open:         key.NewBinding(key.WithKeys("o"), key.WithHelp("o", "open it, or the last one")),
+	copy:         key.NewBinding(key.WithKeys("c"), key.WithHelp("c", "copy its path, or its error")),
+	selectRepo:   key.NewBinding(key.WithKeys("tab", "shift+tab"), key.WithHelp("tab/shift+tab", "select a running repo")),
+	detail:       key.NewBinding(key.WithKeys("d"), key.WithHelp("d", "show its git output")),
+	skip:         key.NewBinding(key.WithKeys("s"), key.WithHelp("s", "skip it")),
+	kill:         key.NewBinding(key.WithKeys("k"), key.WithHelp("k", "kill a hung task")),
+	parallel:     key.NewBinding(key.WithKeys("+", "-"), key.WithHelp("+/-", "more or fewer parallel tasks")),
+	pause:        key.NewBinding(key.WithKeys("p", "P"), key.WithHelp("p/P", "pause, or suspend the running tasks too")),
+	add:          key.NewBinding(key.WithKeys("a"), key.WithHelp("a", "add a root or repo")),
+	confirm:      key.NewBinding(key.WithKeys("y", "n"), key.WithHelp("y/n", "answer a confirmation")),
+	fullScreen:   key.NewBinding(key.WithKeys("f"), key.WithHelp("f", "switch to or from the alternate screen")),
+	help:         key.NewBinding(key.WithKeys("?"), key.WithHelp("?", "close this help")),
+	quit:         key.NewBinding(key.WithKeys("q", "Q", "esc", "ctrl+c"), key.WithHelp("q/esc", "quit, after asking; Q right away")),
+}
+
+func (k keyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.help, k.quit}
+}
+
+func (k keyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.scroll, k.filter, k.group, k.sort, k.selectResult, k.open, k.copy},
+		{k.selectRepo, k.detail, k.skip, k.kill, k.parallel},
+		{k.pause, k.add, k.confirm, k.fullScreen, k.help, k.quit},
+	}
+}
+
+// helpOverlay renders the keys of the UI, and the flags the run was started
+// with.
+func (m model) helpOverlay() string {
+	h := help.New()
+	h.Width = m.width
+
+	var b strings.Builder
+	b.WriteString(m.styles.currentDirName.Render("Keys") + "\n")
+	b.WriteString(h.FullHelpView(keys.FullHelp()) + "\n")
+	if len(m.flags) > 0 {
+		b.WriteString("\n" + m.styles.currentDirName.Render("Flags") + "\n")
+		b.WriteString(lipgloss.NewStyle().Width(max(0, m.width-4)).Render(strings.Join(m.flags, " ")) + "\n")
+	}
+
+	return m.styles.border.Render(strings.TrimSuffix(b.String(), "\n")) + "\n"
+}
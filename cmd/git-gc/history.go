@@ -0,0 +1,120 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// historyLine is a line in the history, about dir unless that's empty.
+type historyLine struct {
+	dir  string
+	text string
+}
+
+// syncHistory updates the history with the lines printed since it was last
+// synced, cutting them to the terminal's width rather than wrapping them,
+// and with the filter, grouped and sorted if the user asked for it. Resetting m.shown
+// makes it cut every line again.
+func (m *model) syncHistory() {
+	for _, line := range m.printed[len(m.shown):] {
+		m.shown = append(m.shown, fit(line.text, m.width))
+	}
+
+	lines := m.shown
+	switch {
+	case m.grouped:
+		lines = m.groupedHistory()
+		for i, line := range lines {
+			lines[i] = fit(line, m.width)
+		}
+	case m.order != orderFinished:
+		lines = nil
+		for _, line := range m.sortedHistory() {
+			if _, ok := fuzzyScore(m.filter, line.dir); m.filter == "" || ok && line.dir != "" {
+				lines = append(lines, fit(line.text, m.width))
+			}
+		}
+	case m.filter != "":
+		lines = nil
+		for i, line := range m.printed {
+			if _, ok := fuzzyScore(m.filter, line.dir); ok && line.dir != "" {
+				lines = append(lines, m.shown[i])
+			}
+		}
+	}
+
+	m.matches = len(lines)
+	m.history.Width = max(0, m.width)
+	m.history.SetContent(strings.Join(lines, "\n"))
+}
+
+// historyHeight is how many lines of history fit above footer: all of them,
+// up to what's left of the terminal.
+func (m model) historyHeight(footer string) int {
+	return min(m.matches, max(0, m.height-lipgloss.Height(footer)))
+}
+
+// setFilter filters the history by filter, following new lines again.
+func (m *model) setFilter(filter string) {
+	m.filter, m.scrolled = filter, false
+	m.syncHistory()
+}
+
+// typeFilter edits the filter as the user types it after pressing /,
+// filtering the history as they go.
+func (m model) typeFilter(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEnter:
+		m.filtering = false
+	case tea.KeyEsc:
+		m.filtering = false
+		m.setFilter("")
+	case tea.KeyCtrlC:
+		m.filtering = false
+		return m, m.quit()
+	default:
+		m.setFilter(editLine(m.filter, msg))
+	}
+
+	return m, nil
+}
+
+// scrollHistory scrolls the history for the pgup, pgdown, home and end keys.
+// Once scrolled back to the end, it follows new lines again.
+func (m *model) scrollHistory(key string) {
+	m.history.Height = m.historyHeight(m.footer())
+	if !m.scrolled {
+		m.history.GotoBottom()
+	}
+
+	switch key {
+	case "pgup":
+		m.history.ViewUp()
+	case "pgdown":
+		m.history.ViewDown()
+	case "home":
+		m.history.GotoTop()
+	case "end":
+		m.history.GotoBottom()
+	}
+
+	m.scrolled = !m.history.AtBottom()
+}
+
+// exit ends the program, printing the history so that it stays in the
+// terminal's scrollback once the summary replaces the UI. Nothing stays
+// behind of the alternate screen.
+func (m model) exit() tea.Cmd {
+	if len(m.printed) == 0 || m.altScreen {
+		return tea.Quit
+	}
+
+	lines := make([]string, len(m.printed))
+	for i, line := range m.printed {
+		lines[i] = line.text
+	}
+
+	return tea.Sequence(tea.Println(strings.Join(lines, "\n")), tea.Quit)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/charmbracelet/bubbletea"
+)
+
+// hooks are shell commands run before and after each repo's pipeline.
+type hooks struct {
+	pre  *template.Template
+	post *template.Template
+}
+
+// hookData is what a hook command template can refer to. Duration, Result
+// and Error are only set for the post hook. Like the fields of execData,
+// Error renders as a reference to an environment variable, GIT_GC_ERROR.
+type hookData struct {
+	execData
+	Duration time.Duration
+	Result   string // "success" or "failure"
+	Error    string
+}
+
+// postHookCompleted is sent when the post hook of a repo exits.
+type postHookCompleted struct {
+	dir string
+	err error
+}
+
+func newHooks(cfg hooksConfig) (hooks, error) {
+	var (
+		h   hooks
+		err error
+	)
+	if h.pre, err = parseHook("pre", cfg.Pre); err != nil {
+		return hooks{}, err
+	}
+
+	if h.post, err = parseHook("post", cfg.Post); err != nil {
+		return hooks{}, err
+	}
+
+	return h, nil
+}
+
+func parseHook(name, command string) (*template.Template, error) {
+	if strings.TrimSpace(command) == "" {
+		return nil, nil
+	}
+
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(command)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse %s hook: %w", name, err)
+	}
+
+	return tmpl, nil
+}
+
+// renderHook renders the command of tmpl for dir, and returns the
+// environment it refers to.
+func renderHook(tmpl *template.Template, dir string, data hookData) (string, []string, error) {
+	var env []string
+	data.execData, env = newExecData(dir)
+	if data.Error != "" {
+		env = append(env, "GIT_GC_ERROR="+data.Error)
+		data.Error = shellVar("GIT_GC_ERROR")
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", nil, fmt.Errorf("%s hook: could not render command: %w", tmpl.Name(), err)
+	}
+
+	return b.String(), env, nil
+}
+
+// runHook renders tmpl with data and runs it through the shell in dir,
+// stopping it like a task when ctx is done. A nil template is a no-op.
+func runHook(ctx context.Context, tmpl *template.Template, dir string, data hookData) error {
+	if tmpl == nil {
+		return nil
+	}
+
+	command, env, err := renderHook(tmpl, dir, data)
+	if err != nil {
+		return err
+	}
+
+	cmd, exited := groupCommand(ctx, dir, shellCommand(command)...)
+	cmd.Env = append(os.Environ(), env...)
+	stderr := &bytes.Buffer{}
+	cmd.Stdout = io.Discard
+	cmd.Stderr = stderr
+
+	err = cmd.Run()
+	exited()
+	if err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("%s hook: %w", tmpl.Name(), contextError(ctx))
+		}
+
+		return fmt.Errorf("%s hook: %w", tmpl.Name(), gitError(err, stderr.String()))
+	}
+
+	return nil
+}
+
+func runPostHook(tmpl *template.Template, dir string, elapsed time.Duration, taskErr error) work {
+	return func(ctx context.Context) tea.Msg {
+		data := hookData{Duration: elapsed.Round(time.Millisecond), Result: "success"}
+		if taskErr != nil {
+			data.Result = "failure"
+			data.Error = taskErr.Error()
+		}
+
+		return postHookCompleted{dir: dir, err: runHook(ctx, tmpl, dir, data)}
+	}
+}
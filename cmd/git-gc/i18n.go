@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// catalogs are the translations of the messages shown to the user, by
+// language. Messages are looked up by their English format string, which is
+// shown as is when there's no translation. Translations can reorder the
+// arguments with explicit indexes, like %[2]d.
+var catalogs = map[string]map[string]string{
+	"ja": catalogJa,
+}
+
+// catalog is the catalog of the user's language, nil for English.
+var catalog = catalogs[userLanguage(os.Getenv)]
+
+// userLanguage returns the language the environment asks messages to be in,
+// the way gettext picks it: from LANGUAGE, LC_ALL, LC_MESSAGES, then LANG.
+// ja_JP.UTF-8 is "ja".
+func userLanguage(getenv func(string) string) string {
+	for _, name := range []string{"LANGUAGE", "LC_ALL", "LC_MESSAGES", "LANG"} {
+		locale, _, _ := strings.Cut(getenv(name), ":")
+		if locale == "" {
+			continue
+		}
+
+		lang, _, _ := strings.Cut(locale, ".")
+		lang, _, _ = strings.Cut(lang, "_")
+		return strings.ToLower(lang)
+	}
+
+	return ""
+}
+
+// tr formats a message in the user's language.
+func tr(format string, args ...any) string {
+	if translated, ok := catalog[format]; ok {
+		format = translated
+	}
+
+	if len(args) == 0 {
+		return format
+	}
+
+	return fmt.Sprintf(format, args...)
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// userIdle returns how long the user hasn't touched the keyboard or mouse,
+// from the HIDIdleTime the IOHIDSystem reports.
+func userIdle() (time.Duration, bool) {
+	out, err := exec.Command("ioreg", "-c", "IOHIDSystem", "-d", "4", "-k", "HIDIdleTime").Output()
+	if err != nil {
+		return 0, false
+	}
+
+	// | |   "HIDIdleTime" = 1234567890
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		_, value, ok := strings.Cut(sc.Text(), `"HIDIdleTime" = `)
+		if !ok {
+			continue
+		}
+
+		ns, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			return 0, false
+		}
+
+		return time.Duration(ns), true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// userIdle returns how long the user hasn't touched the keyboard or mouse,
+// as reported by xprintidle. It's unknown outside of an X session or when
+// xprintidle isn't installed.
+func userIdle() (time.Duration, bool) {
+	if os.Getenv("DISPLAY") == "" {
+		return 0, false
+	}
+
+	out, err := exec.Command("xprintidle").Output()
+	if err != nil {
+		return 0, false
+	}
+
+	ms, err := strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return time.Duration(ms) * time.Millisecond, true
+}
@@ -0,0 +1,11 @@
+//go:build !linux && !darwin && !windows
+
+package main
+
+import "time"
+
+// userIdle returns how long the user hasn't touched the keyboard or mouse,
+// which isn't known here.
+func userIdle() (time.Duration, bool) {
+	return 0, false
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+var (
+	user32               = syscall.NewLazyDLL("user32.dll")
+	kernel32             = syscall.NewLazyDLL("kernel32.dll")
+	procGetLastInputInfo = user32.NewProc("GetLastInputInfo")
+	procGetTickCount     = kernel32.NewProc("GetTickCount")
+)
+
+// lastInputInfo is LASTINPUTINFO.
+type lastInputInfo struct {
+	size uint32
+	time uint32
+}
+
+// userIdle returns how long the user hasn't touched the keyboard or mouse.
+func userIdle() (time.Duration, bool) {
+	info := lastInputInfo{size: uint32(unsafe.Sizeof(lastInputInfo{}))}
+	if ok, _, _ := procGetLastInputInfo.Call(uintptr(unsafe.Pointer(&info))); ok == 0 {
+		return 0, false
+	}
+
+	now, _, _ := procGetTickCount.Call()
+
+	// Both are milliseconds since boot, wrapping around every 49.7 days
+	return time.Duration(uint32(now)-info.time) * time.Millisecond, true
+}
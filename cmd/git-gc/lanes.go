@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// laneBarWidth is the width of the progress bar of a lane.
+const laneBarWidth = 12
+
+// gitProgress matches the progress meters git writes to stderr, e.g.
+// "Compressing objects:  42% (420/1000)", or "remote: Counting objects: 7%"
+// when they're the server's.
+var gitProgress = regexp.MustCompile(`^(?:remote: )?([A-Z][a-z ]+):\s+(\d{1,3})%`)
+
+// progress returns the phase and percentage of the progress meter git is
+// redrawing, if it's drawing one.
+func (t *outputTail) progress() (string, float64, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	match := gitProgress.FindStringSubmatch(lastSegment(t.partial))
+	if match == nil {
+		return "", 0, false
+	}
+
+	percent, _ := strconv.Atoi(match[2])
+	return match[1], min(1, float64(percent)/100), true
+}
+
+// footerNames is how many running repos the status line names.
+const footerNames = 3
+
+// runningNames names the oldest running repos for the status line, so that
+// it says what's busy even without the lanes.
+func (m model) runningNames() string {
+	dirs := m.inFlight()
+	if len(dirs) == 0 {
+		return tr("repos...")
+	}
+
+	names := make([]string, 0, footerNames)
+	for _, dir := range dirs[:min(len(dirs), footerNames)] {
+		names = append(names, filepath.Base(dir))
+	}
+
+	s := strings.Join(names, ", ")
+	if len(dirs) > footerNames {
+		s += tr(" and %d more", len(dirs)-footerNames)
+	}
+
+	return s + " …"
+}
+
+// lanes renders a line per in-flight repo above the progress bar, oldest
+// first, with the task it's on and how long it has been running, and the
+// progress git reports for it, if any. Lanes that don't fit in half the terminal are summed up in a last line.
+func (m model) lanes() string {
+	if len(m.started) == 0 || m.compact {
+		return ""
+	}
+
+	dirs := m.inFlight()
+
+	shown := len(dirs)
+	if m.height > 0 && shown > max(1, m.height/2) {
+		shown = max(1, m.height/2) - 1
+	}
+
+	var b strings.Builder
+	spin := m.spinner.View() + " "
+	shownDir := m.detailDir()
+	for _, dir := range dirs[:shown] {
+		name := m.styles.currentDirName
+		if (m.detail || m.selected != "") && dir == shownDir {
+			name = name.Underline(true)
+		}
+
+		if m.narrow() {
+			b.WriteString(fit(spin+name.Render(truncateLeft(dir, m.width-lipgloss.Width(spin))), m.width) + "\n")
+			continue
+		}
+
+		status := fmt.Sprintf("%s %s", m.current[dir], time.Since(m.started[dir]).Truncate(time.Second))
+		if phase, percent, ok := m.runner.tail(dir).progress(); ok {
+			status = fmt.Sprintf("%s %s %.0f%%, %s", m.laneBar.ViewAs(percent), strings.ToLower(phase), percent*100, status)
+		}
+
+		// The end of the path tells repos apart; the status is cut first,
+		// from half the terminal on
+		shownPath := dir
+		if m.width > 0 {
+			shownPath = truncateLeft(dir, max(m.width/2, m.width-lipgloss.Width(spin+" "+status)))
+		}
+
+		b.WriteString(fit(spin+name.Render(shownPath)+" "+m.styles.note.Render(status), m.width) + "\n")
+	}
+
+	if n := len(dirs) - shown; n > 0 {
+		b.WriteString(m.styles.note.Render("  "+tr("and %d more", n)) + "\n")
+	}
+
+	return b.String()
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// listRepos prints the repos under root one per line for the list command,
+// narrowed down by sh and in order like a run would process them, so that
+// other tools can take them from there. Anything else goes to stderr.
+func listRepos(root string, opts scanOptions, sh shard, order repoOrder) int {
+	scan, err := findDirectories(root, opts)
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, "Error finding repos:", err)
+		return exitError
+	}
+
+	for _, dir := range scan.unresponsive {
+		_, _ = fmt.Fprintf(os.Stderr, "Skipped %s, listing it took longer than %s\n", dir, opts.dirTimeout)
+	}
+
+	if scan.timedOut {
+		_, _ = fmt.Fprintf(os.Stderr, "Stopped looking for repos after %s, only listing the %d found so far\n", opts.timeout, len(scan.dirs))
+	}
+
+	dirs := sh.filter(root, scan.dirs)
+	sortRepos(dirs, order)
+	for _, dir := range dirs {
+		fmt.Println(dir)
+	}
+
+	return exitOK
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"runtime"
+	"time"
+
+	"github.com/charmbracelet/bubbletea"
+)
+
+// loadInterval is how often the system load is sampled.
+const loadInterval = 5 * time.Second
+
+// userIdleAfter is how long the user must have left the keyboard and mouse
+// alone before --when-idle considers the machine idle.
+const userIdleAfter = 5 * time.Minute
+
+// loadSample is how busy the machine was at one point.
+type loadSample struct {
+	load1     float64       // 1 minute load average, -1 if unknown
+	iowait    float64       // fraction of CPU time spent waiting for IO since the last sample, -1 if unknown
+	userIdle  time.Duration // how long since the last keyboard or mouse input
+	idleKnown bool          // whether userIdle is known
+	power     string        // why to save power, e.g. "on battery", "" if not
+}
+
+// loadSampled is sent with every sample the load monitor takes.
+type loadSampled struct {
+	sample loadSample
+	err    error
+}
+
+// loadMonitor samples how busy the machine is, so --auto-parallel can scale
+// the disk pool to what the machine can take, and --when-idle and
+// --on-ac-only can hold off while it's in use or on battery. Samples are
+// taken one at a time, so it needs no locking.
+type loadMonitor struct {
+	cpus     int
+	sample   func() (loadSample, error) // nil if the load can't be read here
+	userIdle bool                       // whether to check for user input too
+	power    bool                       // whether to check the power source too
+}
+
+// newLoadMonitor returns a load monitor, which fails when needLoad is set
+// and the load average can't be read on this platform.
+func newLoadMonitor(needLoad bool) (*loadMonitor, error) {
+	sample, err := newLoadSampler()
+	if err != nil && needLoad {
+		return nil, err
+	}
+
+	return &loadMonitor{cpus: runtime.NumCPU(), sample: sample}, nil
+}
+
+// next takes the next sample after loadInterval.
+func (lm *loadMonitor) next() tea.Cmd {
+	return lm.sampleAfter(loadInterval)
+}
+
+func (lm *loadMonitor) sampleAfter(d time.Duration) tea.Cmd {
+	return tea.Tick(d, func(time.Time) tea.Msg {
+		s, err := loadSample{load1: -1, iowait: -1}, error(nil)
+		if lm.sample != nil {
+			s, err = lm.sample()
+		}
+
+		if lm.userIdle {
+			s.userIdle, s.idleKnown = userIdle()
+		}
+
+		if lm.power {
+			s.power = powerSaving()
+		}
+
+		return loadSampled{sample: s, err: err}
+	})
+}
+
+// adjust returns how to change a pool limit given a sample: down when the
+// CPUs are saturated or the disks are what everything waits for, up when
+// there's plenty of room, and 0 otherwise.
+func (lm *loadMonitor) adjust(s loadSample) int {
+	cpus := float64(lm.cpus)
+	switch {
+	case s.load1 > cpus || s.iowait > 0.3:
+		return -1
+	case s.load1 < 0.7*cpus && s.iowait < 0.1:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// busy returns why the machine isn't idle, or "" if it is. running is how
+// many of the processes adding to the load are git-gc's own.
+func (lm *loadMonitor) busy(s loadSample, running int) string {
+	if s.idleKnown && s.userIdle < userIdleAfter {
+		return tr("Waiting for the machine to be idle, it's in use")
+	}
+
+	if load := s.load1 - float64(running); s.load1 >= 0 && load > 0.5*float64(lm.cpus) {
+		return tr("Waiting for the machine to be idle, load is %.1f", s.load1)
+	}
+
+	return ""
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// newLoadSampler reads the load average with sysctl. macOS doesn't account
+// for IO wait, so only the load average is known.
+func newLoadSampler() (func() (loadSample, error), error) {
+	sample := func() (loadSample, error) {
+		out, err := exec.Command("sysctl", "-n", "vm.loadavg").Output()
+		if err != nil {
+			return loadSample{}, fmt.Errorf("could not read load average: %w", err)
+		}
+
+		// { 1.23 1.45 1.67 }
+		fields := strings.Fields(strings.Trim(strings.TrimSpace(string(out)), "{}"))
+		if len(fields) == 0 {
+			return loadSample{}, fmt.Errorf("unexpected vm.loadavg %q", string(out))
+		}
+
+		load1, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			return loadSample{}, fmt.Errorf("unexpected vm.loadavg %q", string(out))
+		}
+
+		return loadSample{load1: load1, iowait: -1}, nil
+	}
+
+	_, err := sample()
+	return sample, err
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// newLoadSampler reads the load average from /proc/loadavg and the IO wait
+// from the cpu line of /proc/stat.
+func newLoadSampler() (func() (loadSample, error), error) {
+	var prevIOWait, prevTotal uint64
+	sample := func() (loadSample, error) {
+		b, err := os.ReadFile("/proc/loadavg")
+		if err != nil {
+			return loadSample{}, fmt.Errorf("could not read load average: %w", err)
+		}
+
+		fields := strings.Fields(string(b))
+		if len(fields) == 0 {
+			return loadSample{}, fmt.Errorf("unexpected /proc/loadavg %q", string(b))
+		}
+
+		load1, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			return loadSample{}, fmt.Errorf("unexpected /proc/loadavg %q", string(b))
+		}
+
+		s := loadSample{load1: load1, iowait: -1}
+		iowait, total, err := cpuTimes()
+		if err == nil && prevTotal > 0 && total > prevTotal {
+			s.iowait = float64(iowait-prevIOWait) / float64(total-prevTotal)
+		}
+
+		prevIOWait, prevTotal = iowait, total
+		return s, nil
+	}
+
+	_, err := sample()
+	return sample, err
+}
+
+// cpuTimes returns the time all CPUs spent waiting for IO, and in total.
+func cpuTimes() (iowait, total uint64, err error) {
+	b, err := os.ReadFile("/proc/stat")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// cpu user nice system idle iowait irq softirq steal ...
+	line, _, _ := strings.Cut(string(b), "\n")
+	fields := strings.Fields(line)
+	if len(fields) < 6 || fields[0] != "cpu" {
+		return 0, 0, fmt.Errorf("unexpected /proc/stat line %q", line)
+	}
+
+	for i, f := range fields[1:] {
+		n, err := strconv.ParseUint(f, 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("unexpected /proc/stat line %q", line)
+		}
+
+		total += n
+		if i == 4 {
+			iowait = n
+		}
+	}
+
+	return iowait, total, nil
+}
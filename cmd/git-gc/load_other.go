@@ -0,0 +1,12 @@
+//go:build !linux && !darwin
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func newLoadSampler() (func() (loadSample, error), error) {
+	return nil, fmt.Errorf("reading the system load isn't supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pidLock is the owner of a lock recorded in a pid file, such as the gc.pid
+// git writes while `git gc` runs and removes when it's done.
+type pidLock struct {
+	pid  int
+	host string
+}
+
+// absoluteGitDir returns the git directory of the repo at dir, which isn't
+// necessarily dir/.git for worktrees and submodules.
+func absoluteGitDir(ctx context.Context, dir string) (string, error) {
+	out, err := gitOutput(ctx, dir, "rev-parse", "--absolute-git-dir")
+	if err != nil {
+		return "", fmt.Errorf("could not find git dir: %w", err)
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
+
+// readGCLock reads gitDir/gc.pid, returning ok=false when there is none.
+func readGCLock(gitDir string) (pidLock, bool, error) {
+	return readPIDLock(filepath.Join(gitDir, "gc.pid"))
+}
+
+// readPIDLock reads a "PID HOSTNAME" pid file, returning ok=false when there
+// is none.
+func readPIDLock(path string) (pidLock, bool, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return pidLock{}, false, nil
+	}
+
+	if err != nil {
+		return pidLock{}, false, err
+	}
+
+	lock, err := parsePIDLock(path, b)
+	if err != nil {
+		return pidLock{}, false, err
+	}
+
+	return lock, true, nil
+}
+
+// parsePIDLock parses b, the contents of the pid file at path.
+func parsePIDLock(path string, b []byte) (pidLock, error) {
+	pidStr, host, _ := strings.Cut(strings.TrimSpace(string(b)), " ")
+	pid, err := strconv.Atoi(pidStr)
+	if err != nil {
+		return pidLock{}, fmt.Errorf("malformed pid file %s: %q", path, string(b))
+	}
+
+	return pidLock{pid: pid, host: host}, nil
+}
+
+// held reports whether the lock's owner is still running. Locks taken on
+// another host can't be checked, so they're assumed to be held.
+func (l pidLock) held() bool {
+	if hostname, err := os.Hostname(); err != nil || hostname != l.host {
+		return true
+	}
+
+	return processAlive(l.pid)
+}
+
+// staleGCFiles returns the gc.pid and gc.log files in gitDir left behind by a
+// gc that crashed. A gc.log makes every later `git gc --auto` a silent no-op
+// until it expires.
+func staleGCFiles(gitDir string) ([]string, error) {
+	lock, locked, err := readGCLock(gitDir)
+	if err != nil {
+		return nil, err
+	}
+
+	if locked && lock.held() {
+		return nil, nil
+	}
+
+	var stale []string
+	for _, name := range []string{"gc.pid", "gc.log"} {
+		if _, err := os.Stat(filepath.Join(gitDir, name)); err == nil {
+			stale = append(stale, name)
+		}
+	}
+
+	return stale, nil
+}
+
+// instanceRunningError is returned when another git-gc holds the lock of
+// the same root.
+type instanceRunningError struct {
+	owner pidLock
+	root  string
+}
+
+func (e *instanceRunningError) Error() string {
+	return fmt.Sprintf("another git-gc (pid %d on %s) is already running in %s", e.owner.pid, e.owner.host, e.root)
+}
+
+// instanceLockPath returns the lock file of root, e.g.
+// ~/.cache/git-gc/locks/home-1a2b3c4d.lock, or "" if there's no cache dir.
+func instanceLockPath(root string) string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+
+	sum := sha256.Sum256([]byte(root))
+	return filepath.Join(dir, "git-gc", "locks", filepath.Base(root)+"-"+hex.EncodeToString(sum[:4])+".lock")
+}
+
+// lockInstance takes the lock that keeps two git-gc runs from working on
+// the same root at once, taking over locks left behind by runs that died.
+// The returned func releases it.
+func lockInstance(root string) (func(), error) {
+	path := instanceLockPath(root)
+	if path == "" {
+		return func() {}, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("could not create lock dir: %w", err)
+	}
+
+	release, owner, err := takePIDLock(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if release == nil {
+		return nil, &instanceRunningError{owner: owner, root: root}
+	}
+
+	return release, nil
+}
+
+// takePIDLock creates the pid file at path, taking over one left behind by
+// a process that died. The returned func releases it. While a live process
+// holds it, it returns that process as the owner and a nil func instead.
+func takePIDLock(path string) (func(), pidLock, error) {
+	hostname, _ := os.Hostname()
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			_, err = fmt.Fprintf(f, "%d %s\n", os.Getpid(), hostname)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+
+			if err != nil {
+				_ = os.Remove(path)
+				return nil, pidLock{}, fmt.Errorf("could not write lock file: %w", err)
+			}
+
+			return func() { _ = os.Remove(path) }, pidLock{}, nil
+		}
+
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, pidLock{}, fmt.Errorf("could not create lock file: %w", err)
+		}
+
+		info, b, err := readLockFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+
+		if err != nil {
+			return nil, pidLock{}, fmt.Errorf("could not read lock file: %w", err)
+		}
+
+		owner, err := parsePIDLock(path, b)
+		if err == nil && owner.held() {
+			return nil, owner, nil
+		}
+
+		// Its owner may not have written its pid yet. If it's been a while,
+		// it died before it did.
+		if err != nil && time.Since(info.ModTime()) < time.Second {
+			time.Sleep(10 * time.Millisecond)
+			continue
+		}
+
+		if err := removeStaleLock(path, info); err != nil {
+			return nil, pidLock{}, err
+		}
+	}
+}
+
+// readLockFile reads the lock file at path, along with what identifies it.
+func readLockFile(path string) (os.FileInfo, []byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	b, err := io.ReadAll(f)
+	return info, b, err
+}
+
+// removeStaleLock removes the lock file at path if it's still stale, the
+// one found stale. Another process may have found it stale too, and taken
+// it over in the meantime, so it's moved aside first, which only one of
+// them can do, and put back if it turns out to be a new one.
+func removeStaleLock(path string, stale os.FileInfo) error {
+	aside := fmt.Sprintf("%s.%d.stale", path, os.Getpid())
+	if err := os.Rename(path, aside); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+
+		return fmt.Errorf("could not remove stale lock file: %w", err)
+	}
+	defer os.Remove(aside)
+
+	if info, err := os.Stat(aside); err == nil && os.SameFile(info, stale) {
+		return nil
+	}
+
+	// Linking fails if yet another lock was taken since, rather than
+	// replacing it
+	if err := os.Link(aside, path); err != nil && !errors.Is(err, fs.ErrExist) {
+		return fmt.Errorf("could not put back lock file: %w", err)
+	}
+
+	return nil
+}
@@ -1,22 +1,27 @@
 package main
 
 import (
+	"cmp"
+	"context"
 	"errors"
 	"flag"
 	"fmt"
-	"io"
+	"log"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"runtime"
 	"slices"
 	"strings"
+	"text/template"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/charmbracelet/bubbles/progress"
 	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/viewport"
 	"github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
-	"github.com/ugurcsen/gods-generic/sets/hashset"
 )
 
 type model struct {
@@ -26,246 +31,1924 @@ type model struct {
 
 	spinner  spinner.Model
 	progress progress.Model
+	laneBar  progress.Model // draws the progress git reports in each lane
 
-	done        bool
-	concurrency int // user-supplied concurrency
-	inFlight    int // how many GCs are currently running
-	nextIndex   int // which dir to spawn next
-	index       int // how many GCs completed
+	// history scrolls through the lines printed about finished repos, shown
+	// above the progress bar. scrolled is set while the user scrolled away
+	// from the latest line.
+	history  viewport.Model
+	printed  []historyLine // the lines in history, as printed
+	shown    []string      // the lines in history, cut to the terminal's width
+	scrolled bool
+
+	// filter only shows the lines in history about repos fuzzily matching
+	// it; matches is how many there are. filtering is set while the user
+	// types it.
+	filter    string
+	filtering bool
+	matches   int
+	grouped   bool         // the history groups repos by their parent directory
+	order     historyOrder // the order the history lists repos in
+
+	done     bool
+	pipeline []task                   // tasks to run, in order, in each repo
+	failures []repoFailure            // repos where a task exited non-zero
+	pools    [numTaskKinds]pool       // independent concurrency limits per task kind
+	devices  deviceLimits             // concurrency limits of disk-bound tasks per device
+	hooks    hooks                    // pre/post hooks from the config file
+	checks   preflight                // run on each repo before its first task
+	skipped  []repoSkip               // repos the preflight checks decided to leave alone
+	confirms []confirmRequest         // tasks waiting for the user to answer y/n, oldest first
+	started  map[string]time.Time     // when each in-flight repo started its first task
+	current  map[string]string        // the task each in-flight repo ran last, for its lane
+	notes    map[string][]string      // task notes of each in-flight repo
+	sizes    map[string]int64         // git dir size of each in-flight repo before its first task
+	freed    map[string]int64         // space freed by each in-flight repo whose tasks all succeeded
+	reclaim  int64                    // space freed by all repos so far
+	took     map[string]time.Duration // how long each finished repo took
+	past     map[string]time.Duration // how long each repo took the last time it succeeded, for the ETA
+	finished map[string]bool          // the repos that are done
+	results  map[string]repoResult    // how each repo that's done ended
+	weights  repoWeights              // what each repo weighs in the progress bar, nil until measured
+	lastDone time.Time                // when the last repo finished
+	retries  int                      // how often to retry a task that failed transiently
+	attempts map[string]int           // retries so far of the current task of each in-flight repo
+	rerun    bool                     // run failed repos again once every repo is done
+	failFast bool                     // stop everything at the first failure instead of keeping going
+
+	// load samples how busy the machine is when autoParallel, whenIdle or
+	// onACOnly need it. autoParallel scales the disk pool between 1 and maxParallel
+	// to the load, until the user sets the limits by hand.
+	load         *loadMonitor
+	autoParallel bool
+	maxParallel  int
+	whenIdle     bool     // only start tasks while the machine is idle
+	idleWait     string   // why whenIdle holds off new tasks, "" if it doesn't
+	onACOnly     bool     // only start tasks while the machine is plugged in
+	powerWait    string   // why onACOnly holds off new tasks, "" if it doesn't
+	reran        []string // repos that failed the first time, once they're run again
+	offerRetry   bool     // the summary waits for r to run the failed repos again
+	budget       budget   // how much the run may do
+	begun        int      // how many repos started their first task
+	remaining    []string // repos left for the next run once the budget ran out
+	journal      *runJournal
+	startAt      time.Time     // when the first task may start
+	stagger      time.Duration // minimum time between starting two tasks
+	nextSpawn    time.Time     // when the next task may start because of stagger
+	spawnRate    *tokenBucket  // limits how fast tasks start, nil for no limit
+	watchdog     watchdog
+	hung         []hungTask // running tasks that look hung, longest idle first
+	index        int        // how many GCs completed
+	runner       *runner    // runs tasks and hooks in the background
+	running      int        // tasks and hooks started on the runner that haven't reported back
+
+	// quitting is set once the user asked to quit: no new tasks start, and
+	// the program exits when the running ones are done. Unless waitOnQuit
+	// is set, they're stopped rather than waited for.
+	quitting   bool
+	waitOnQuit bool
+	unattended bool // nobody answers confirmations, so they're declined
+	paused     bool // no new tasks start until unpaused
+	frozen     bool // the running tasks are suspended while paused
+
+	// adding is set while the user types the path of another root or repo
+	// to add to the run, addInput. scan is how repos are looked for in it.
+	adding   bool
+	addInput string
+	scan     scanOptions
+
+	title   bool // show the progress in the terminal's title
+	compact bool // only show the progress bar's line, no lanes or history
+
+	confirmQuit bool // set while asking whether to abort the run after q
+
+	// accessible logs the status now and then for screen readers, along
+	// with the plain lines.
+	accessible bool
+
+	// altScreen is set while the UI is in the terminal's alternate screen,
+	// where the history isn't printed when the program ends.
+	altScreen bool
+
+	// showHelp is set while the help overlay is open, which lists the keys
+	// and flags, the flags the run was started with.
+	showHelp bool
+	flags    []string
+
+	// detail is set while the pane tailing the git output of an in-flight
+	// repo is open; selected is the repo picked for it with tab.
+	detail   bool
+	selected string
+
+	porcelain *porcelain // writes what happens as JSON lines, if set
+	tap       *tapWriter // writes the results in TAP, if set
+
+	// result is the finished repo selected with the arrow keys, which o
+	// opens with opener, or the file manager if that's nil.
+	result string
+	opener *template.Template
+
+	clipboard clipboardWrite // what c copied, until it's drawn
+
+	plain      *log.Logger // logs a line per repo instead of showing the UI, nil for the UI
+	quiet      bool        // without the UI, doesn't log a line per repo either
+	stopped    bool        // the running tasks were asked to stop
+	killed     bool        // the running tasks' processes were killed
+	failedFast bool        // the run was stopped because of failFast
+
+	repoTimeout time.Duration // how long a repo's whole pipeline may take, zero for no limit
 
 	styles styles
 }
 
 type styles struct {
 	checkmark      lipgloss.Style
+	cross          lipgloss.Style
+	note           lipgloss.Style
 	start          lipgloss.Style
 	done           lipgloss.Style
 	currentDirName lipgloss.Style
+	paused         lipgloss.Style
+	border         lipgloss.Style // boxes such as the detail pane
 }
 
-type dirGitGCCompleted string
+// runStarted kicks off the initial batch of repos once the program is running.
+type runStarted struct{}
+
+// taskCompleted is sent when a single task of a repo's pipeline exits.
+type taskCompleted struct {
+	dir  string
+	step int    // index of the task in the pipeline
+	note string // why the task ran the way it did, if it had a choice
+	skip string // why the whole repo was skipped, if it was
+	err  error
+
+	transient bool     // err is likely to go away when the task is retried
+	output    []string // the last lines the failed command wrote to stderr
+
+	// The size of the repo's git dir before its first task and after its
+	// last one succeeded, 0 if not measured
+	sizeBefore int64
+	sizeAfter  int64
+}
+
+// pool limits how many tasks of one kind run at once. Tasks waiting for a
+// slot sit in the queue in the order they became runnable.
+type pool struct {
+	limit    int
+	inFlight int
+	queue    []job
+}
+
+// job is a single pipeline step of a repo waiting to run.
+type job struct {
+	dir  string
+	step int
+
+	// inv is the already resolved (and confirmed) invocation of a step that
+	// needed the user's go-ahead; nil for steps that haven't been resolved.
+	inv *invocation
+}
+
+// confirmRequest is sent when a task needs the user's go-ahead before it
+// runs its command in a repo.
+type confirmRequest struct {
+	job job
+	inv invocation
+}
+
+type repoSkip struct {
+	dir    string
+	reason string
+}
+
+// repoStatus is how a repo's pipeline ended.
+type repoStatus int
+
+const (
+	statusSucceeded repoStatus = iota
+	statusFailed
+	statusSkipped
+)
+
+// Exit codes, so scripts can tell a clean run from a partial failure.
+const (
+	exitOK      = 0   // every repo succeeded or was skipped
+	exitError   = 1   // nothing ran: bad flags or config, bad root, git missing, ...
+	exitUsage   = 2   // unknown command or flag
+	exitFailed  = 3   // some repos failed
+	exitLocked  = 4   // another git-gc is already running in the same root
+	exitStopped = 130 // the user quit before every repo was done
+)
+
+type repoFailure struct {
+	dir    string
+	task   string
+	err    error
+	output []string // the end of what the task wrote to stderr, if anything
+}
 
 func main() {
+	args := os.Args[1:]
+
+	var command, action string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	if command == "schedule" {
+		action, args = scheduleAction(args)
+	}
+
+	switch command {
+	case "", "verify", "exec", "list", "daemon", "status", "run-now", "pause", "unpause", "add", "schedule":
+	default:
+		fmt.Printf("Unknown command %q\n", command)
+		usage()
+		os.Exit(exitUsage)
+	}
+
 	var (
-		rootDir  string
-		parallel int
+		rootDir       string
+		scanTimeout   time.Duration
+		dirTimeout    time.Duration
+		parallel      int
+		parallelNet   int
+		parallelDisk  int
+		repack        bool
+		repackFlags   string
+		taskList      string
+		timeouts      string
+		execCommand   string
+		configPath    string
+		strategyName  string
+		shallow       string
+		annex         string
+		pruneGone     string
+		pruneUnmerged bool
+		cleanStale    bool
+		orderName     string
+		maxRepos      int
+		resume        bool
+		changedOnly   bool
+		stagger       time.Duration
+		startJitter   time.Duration
+		spawnRate     float64
+		spawnBurst    int
+		hungAfter     time.Duration
+		every         string
+		once          bool
+		noTUI         bool
+		altScreen     bool
+		notify        bool
+		report        string
+		openReport    bool
+		reportFormat  string
+		porcelainOut  bool
+		tapOut        bool
+		title         bool
+		compact       bool
+		yes           bool
+		dryRun        bool
+		accessible    bool
+		colorName     string
+		all           bool
+		quiet         bool
+		verbose       bool
+		maxDuration   time.Duration
+		shardSpec     string
+		minFree       string
+		pruneLoose    string
+		backupDir     string
+		backupKeep    string
+		gitExe        string
+		waitOnQuit    bool
+		repoTimeout   time.Duration
+		retries       int
+		waitForLock   bool
+		autoParallel  bool
+		deviceList    string
+		lowPriority   bool
+		whenIdle      bool
+		onACOnly      bool
+		rerun         bool
+		failFast      bool
+		aggrEvery     string
+		keepLargest   bool
+		bigPack       string
+		packMemory    string
+		limitMemory   string
+		limitCPUs     float64
 	)
 	flag.StringVar(&rootDir, "root", "", "Root directory to search for git repos")
+	flag.DurationVar(&scanTimeout, "scan-timeout", 0, "Stop looking for repos after this long (e.g. 5m) and process the ones found so far; 0 means no limit")
+	flag.DurationVar(&dirTimeout, "scan-dir-timeout", 10*time.Second, "Skip directories that take longer than this to list, such as wedged network mounts; 0 means no limit")
+	flag.StringVar(&gitExe, "git", "git", "Path or name of the git executable to run")
 	flag.IntVar(&parallel, "parallel", runtime.NumCPU(), "Number of parallel git gc processes to run")
-	flag.Parse()
+	flag.StringVar(&deviceList, "parallel-device", "", "Comma separated limits of parallel disk-bound tasks per device, each given by a path on it (e.g. \"/=8,/Volumes/External=1\")")
+	flag.BoolVar(&lowPriority, "low-priority", false, "Run tasks with reduced CPU and IO priority so they don't interfere with foreground work")
+	flag.BoolVar(&onACOnly, "on-ac-only", false, "Only start tasks while the machine is plugged in and not in low power mode")
+	flag.BoolVar(&whenIdle, "when-idle", false, "Only start tasks while the machine is idle: low load and no keyboard or mouse input for 5 minutes")
+	flag.BoolVar(&autoParallel, "auto-parallel", false, "Scale the number of parallel disk-bound tasks between 1 and --parallel-disk to the system load and IO wait")
+	flag.IntVar(&parallelNet, "parallel-net", 0, "Number of parallel network-bound tasks (fetch, remote-prune) to run; defaults to --parallel")
+	flag.IntVar(&parallelDisk, "parallel-disk", 0, "Number of parallel disk-bound tasks (gc, repack, fsck, lfs-prune) to run; defaults to --parallel")
+	flag.BoolVar(&repack, "repack", false, "Run 'git repack -a -d --write-bitmap-index' instead of 'git gc'")
+	flag.StringVar(&repackFlags, "repack-flags", "", "Extra flags to pass to git repack when --repack is set (e.g. \"--window=250 --depth=50\")")
+	flag.StringVar(&orderName, "order", string(orderAlpha), "Order to process repos in: alpha, size-desc (largest first), size-asc, random, or mtime (most recently changed first)")
+	flag.DurationVar(&stagger, "stagger", 0, "Wait at least this long between starting two tasks (e.g. 2s)")
+	flag.Float64Var(&spawnRate, "spawn-rate", 0, "Start at most this many tasks per second on average (e.g. 0.5); 0 means no limit")
+	flag.IntVar(&spawnBurst, "spawn-burst", 1, "How many tasks --spawn-rate lets start at once after a quiet period")
+	flag.StringVar(&every, "every", "daily", "How often git-gc daemon runs, or git-gc schedule install schedules runs: hourly, daily, weekly, monthly, or an interval (e.g. 12h)")
+	flag.BoolVar(&once, "once", false, "Make git-gc daemon run once and exit, as the runs git-gc schedule install sets up do")
+	flag.DurationVar(&hungAfter, "hung-after", 10*time.Minute, "Flag tasks that neither wrote output nor used CPU time for this long as possibly hung; 0 turns it off")
+	flag.DurationVar(&startJitter, "start-jitter", 0, "Wait a random time up to this long before starting (e.g. 10m), so machines on the same schedule don't all start at once")
+	flag.BoolVar(&changedOnly, "changed-since-last-run", false, "Skip repos whose HEAD and objects haven't changed since their last successful run")
+	flag.BoolVar(&resume, "resume", false, "Continue an interrupted run, only processing the repos it didn't get to")
+	flag.IntVar(&maxRepos, "max-repos", 0, "Start at most this many repos, leaving the rest for the next run; 0 means no limit")
+	flag.DurationVar(&maxDuration, "max-duration", 0, "Stop starting repos after this long (e.g. 20m), finishing the running ones and leaving the rest for the next run; 0 means no limit")
+	flag.StringVar(&shardSpec, "shard", "", "Only process shard i of n (e.g. 3/7), a deterministic part of the repos, to split them across nights or machines")
+	flag.StringVar(&taskList, "tasks", "", "Comma separated tasks to run sequentially in each repo ("+strings.Join(taskNames, ", ")+"); defaults to gc, or fsck for verify")
+	flag.DurationVar(&repoTimeout, "timeout", 0, "Stop a repo's tasks once they've run this long in total (e.g. 30m) and report it as failed; 0 means no limit")
+	flag.IntVar(&retries, "retries", 0, "Retry a task that failed because of lock contention or a network hiccup up to this many times, with exponential backoff")
+	flag.BoolVar(&failFast, "fail-fast", false, "Stop at the first failed repo, stopping the running tasks too, instead of keeping going")
+	flag.BoolVar(&rerun, "rerun-failed", false, "Once every repo is done, run the ones that failed again")
+	flag.StringVar(&timeouts, "task-timeout", "", "Comma separated per-task timeouts (e.g. \"fetch=2m,gc=30m\"); timed out tasks are reported as failures")
+	flag.StringVar(&execCommand, "exec", "", "Shell command to run in each repo as the exec task; a template with {{.Repo}} and {{.Name}}")
+	flag.StringVar(&strategyName, "strategy", string(strategyNormal), "How the gc task collects garbage: normal, auto (gc --auto), aggressive, or adaptive (chosen per repo)")
+	flag.StringVar(&aggrEvery, "aggressive-every", "", "Upgrade normal gc to --aggressive when the repo's last aggressive run is older than this (e.g. 30d)")
+	flag.BoolVar(&keepLargest, "keep-largest-pack", false, "Pass --keep-largest-pack to git gc so the largest pack isn't rewritten")
+	flag.StringVar(&limitMemory, "limit-memory", "", "Limit the memory of git-gc and every process it starts together to this size (e.g. 4g), with a cgroup on Linux or a job object on Windows")
+	flag.Float64Var(&limitCPUs, "limit-cpus", 0, "Limit git-gc and every process it starts together to this many CPUs' worth of time (e.g. 1.5), with a cgroup on Linux or a job object on Windows")
+	flag.StringVar(&packMemory, "pack-memory", "", "Limit the memory each git pack-objects uses for deltas to about this size (e.g. 512m), through pack.threads, pack.windowMemory and pack.deltaCacheSize")
+	flag.StringVar(&bigPack, "big-pack-threshold", "", "Run git gc with gc.bigPackThreshold set to this size (e.g. 2g); packs larger than it are kept")
+	flag.StringVar(&shallow, "shallow", string(shallowGC), "What to do with shallow clones: gc (like full clones), skip, or unshallow (fetch --unshallow first)")
+	flag.StringVar(&annex, "annex", string(annexSkip), "What to do with git-annex repos: skip, safe (gc without pruning), or gc (like any other repo)")
+	flag.StringVar(&pruneGone, "prune-gone-branches", string(pruneGoneOff), "Delete local branches whose upstream is gone: off, dry-run (only list them), confirm (ask per repo), or yes")
+	flag.BoolVar(&pruneUnmerged, "prune-gone-unmerged", false, "Also delete gone branches that aren't merged into HEAD, e.g. after a squash merge, with git branch -D instead of -d")
+	flag.StringVar(&pruneLoose, "prune-loose", "", "When gc warns about too many unreachable loose objects, run 'git prune --expire' with this date (e.g. now or 1.day.ago); otherwise only report it")
+	flag.StringVar(&backupDir, "backup-dir", "", "Bundle all refs of a repo into this directory before aggressive gc, pruning everything unreachable, or deleting branches")
+	flag.StringVar(&backupKeep, "backup-retention", "30d", "Delete a repo's backup bundles older than this (e.g. 2w), keeping the newest; 0 keeps them forever")
+	flag.StringVar(&minFree, "min-free-space", "1g", "Skip repos whose disk has less free space than their packs plus this (e.g. 5g); 0 turns the check off")
+	flag.BoolVar(&cleanStale, "clean-stale-locks", false, "Remove gc.pid and gc.log files left behind by a crashed gc whose process is gone")
+	flag.BoolVar(&waitForLock, "wait-for-lock", false, "When another git-gc is already running in the same root, wait for it to finish instead of exiting")
+	flag.BoolVar(&quiet, "quiet", false, "Only log failed repos and a one-line summary at the end, without the UI")
+	flag.BoolVar(&quiet, "q", false, "Shorthand for --quiet")
+	flag.BoolVar(&verbose, "verbose", false, "Show what git writes to stderr as it runs, prefixed with the repo")
+	flag.BoolVar(&verbose, "v", false, "Shorthand for --verbose")
+	flag.StringVar(&colorName, "color", string(colorAuto), "When to color the output: auto (on terminals, unless NO_COLOR is set), always, or never")
+	flag.BoolVar(&altScreen, "alt-screen", false, "Show the UI in the terminal's alternate screen, leaving only the summary behind in the scrollback")
+	flag.BoolVar(&notify, "notify", false, "Ring the terminal bell and show a desktop notification with the summary when the run is over")
+	flag.StringVar(&report, "report", "", "Write a JSON report of the run to this file once it's over: how each repo ended, how long it took and its size before and after")
+	flag.BoolVar(&openReport, "open", false, "Open the --report file once it's written, e.g. an HTML report in the browser")
+	flag.StringVar(&reportFormat, "report-format", "", "The format of --report: json, csv or tsv with a row per repo, markdown, html with charts, or junit; defaults to the file's extension, or else json")
+	flag.BoolVar(&porcelainOut, "porcelain", false, "Write a JSON object per event (scan-started, repo-found, repo-started, repo-finished, run-finished) to stdout instead of showing the UI, for wrappers; the summary goes to stderr")
+	flag.BoolVar(&tapOut, "tap", false, "Write the results to stdout in the Test Anything Protocol instead of showing the UI, a test per repo, for TAP harnesses; the summary goes to stderr")
+	flag.BoolVar(&accessible, "accessible", false, "Log plain lines for screen readers instead of showing the UI, without animations or timestamps, and how far along the run is every 30s")
+	flag.BoolVar(&compact, "compact", false, "Only show the line with the progress bar, without a line per running or finished repo")
+	flag.BoolVar(&title, "title", false, "Show the progress in the terminal's title, e.g. git-gc 42/180 (23%), which tmux shows with set-titles on")
+	flag.BoolVar(&noTUI, "no-tui", false, "Log plain timestamped lines instead of showing the UI, as when the output isn't a terminal")
+	flag.BoolVar(&dryRun, "dry-run", false, "Print the repos that would be processed and the commands that would run in each, without running them")
+	flag.BoolVar(&yes, "yes", false, "Run without asking for confirmation first")
+	flag.BoolVar(&yes, "y", false, "Shorthand for --yes")
+	flag.BoolVar(&all, "all", false, "Run on every repo found, without showing the screen to pick them first")
+	flag.BoolVar(&waitOnQuit, "wait-on-quit", false, "On q or Ctrl+C, let running tasks finish instead of stopping them; pressing it again stops them")
+	flag.StringVar(&configPath, "config", "", "Path to the TOML config file (default "+cmp.Or(defaultConfigPath(), "none")+")")
+	flag.Usage = usage
+	_ = flag.CommandLine.Parse(args)
+
+	if command == "exec" && flag.NArg() > 0 {
+		execCommand = strings.Join(flag.Args(), " ")
+	}
+
+	if err := setGitPath(gitExe); err != nil {
+		fmt.Println("Error checking git:", err)
+		os.Exit(exitError)
+	}
 
-	m, err := newModel(rootDir, parallel)
+	taskTimeouts, err := parseTaskTimeouts(timeouts)
 	if err != nil {
-		fmt.Println("Error creating new model:", err)
-		os.Exit(1)
+		fmt.Println("Error parsing task timeouts:", err)
+		os.Exit(exitError)
 	}
 
-	if _, err := tea.NewProgram(m).Run(); err != nil {
-		fmt.Println("Error running program:", err)
-		os.Exit(1)
+	gcStrategy, err := parseStrategy(strategyName)
+	if err != nil {
+		fmt.Println("Error parsing strategy:", err)
+		os.Exit(exitError)
 	}
-}
 
-func (m model) Init() tea.Cmd {
-	spinnerCmd := m.spinner.Tick
+	var aggressiveEvery time.Duration
+	if aggrEvery != "" {
+		if aggressiveEvery, err = parseInterval(aggrEvery); err != nil {
+			fmt.Println("Error parsing --aggressive-every:", err)
+			os.Exit(exitError)
+		}
+	}
 
-	// If no directories at all, just quit
-	if len(m.directories) == 0 {
-		return tea.Sequence(
-			spinnerCmd,
-			tea.Quit,
-		)
+	per, err := parsePeriod(every)
+	if err != nil {
+		fmt.Println("Error parsing --every:", err)
+		os.Exit(exitError)
 	}
 
-	// Start up to “concurrency” processes
-	toSpawn := min(m.concurrency, len(m.directories))
-	initialCmds := make([]tea.Cmd, toSpawn)
-	for i := range toSpawn {
-		initialCmds[i] = runGitGC(m.directories[m.nextIndex])
-		m.nextIndex++
-		m.inFlight++
+	shallowPol, err := parseShallowPolicy(shallow)
+	if err != nil {
+		fmt.Println("Error parsing --shallow:", err)
+		os.Exit(exitError)
 	}
 
-	// Return a batch of all initial GCs plus the spinner
-	return tea.Batch(
-		spinnerCmd,
-		tea.Batch(initialCmds...),
-	)
-}
+	annexPol, err := parseAnnexPolicy(annex)
+	if err != nil {
+		fmt.Println("Error parsing --annex:", err)
+		os.Exit(exitError)
+	}
 
-func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
-	switch msg := msg.(type) {
-	case tea.WindowSizeMsg:
-		m.width, m.height = msg.Width, msg.Height
-		return m, nil
-	case tea.KeyMsg:
-		switch msg.String() {
-		case "ctrl+c", "esc", "q":
-			return m, tea.Quit
+	pruneGoneMode, err := parsePruneGoneMode(pruneGone)
+	if err != nil {
+		fmt.Println("Error parsing --prune-gone-branches:", err)
+		os.Exit(exitError)
+	}
+
+	var bigPackSize int64
+	if bigPack != "" {
+		if bigPackSize, err = parseSize(bigPack); err != nil {
+			fmt.Println("Error parsing --big-pack-threshold:", err)
+			os.Exit(exitError)
 		}
-	case dirGitGCCompleted:
-		// `msg` is the directory that just finished
-		pkg := string(msg)
+	}
+
+	order, err := parseRepoOrder(orderName)
+	if err != nil {
+		fmt.Println("Error parsing --order:", err)
+		os.Exit(exitError)
+	}
 
-		m.index++
-		m.inFlight--
+	format, err := parseReportFormat(reportFormat, report)
+	if err != nil {
+		fmt.Println("Error parsing --report-format:", err)
+		os.Exit(exitError)
+	}
 
-		// Update our progress bar
-		progressCmd := m.progress.SetPercent(
-			float64(m.index) / float64(len(m.directories)),
-		)
-		// Print checkmark for the completed directory
-		checkMarkCmd := tea.Printf("%s %s", m.styles.checkmark, pkg)
+	if openReport && report == "" {
+		fmt.Println("Error: --open needs a --report file to open")
+		os.Exit(exitError)
+	}
+
+	colors, err := parseColorMode(colorName)
+	if err != nil {
+		fmt.Println("Error parsing --color:", err)
+		os.Exit(exitError)
+	}
+
+	if accessible {
+		colors = colorNever
+	}
+
+	colors.apply()
+
+	sh, err := parseShard(shardSpec)
+	if err != nil {
+		fmt.Println("Error parsing --shard:", err)
+		os.Exit(exitError)
+	}
+
+	minFreeSize, err := parseSize(minFree)
+	if err != nil {
+		fmt.Println("Error parsing --min-free-space:", err)
+		os.Exit(exitError)
+	}
 
-		// If we still have more directories, spawn another
-		var nextCmd tea.Cmd
-		if m.nextIndex < len(m.directories) {
-			nextCmd = runGitGC(m.directories[m.nextIndex])
-			m.nextIndex++
-			m.inFlight++
+	var backupRetention time.Duration
+	if backupKeep != "0" {
+		if backupRetention, err = parseInterval(backupKeep); err != nil {
+			fmt.Println("Error parsing --backup-retention:", err)
+			os.Exit(exitError)
 		}
+	}
+
+	st, err := loadState(defaultStatePath())
+	if err != nil {
+		fmt.Println("Error loading state:", err)
+		os.Exit(exitError)
+	}
+
+	list := defaultTaskList(taskList, command, repack, execCommand != "")
+	if pruneGoneMode != pruneGoneOff {
+		list = withPruneGone(list)
+	}
+
+	pipeline, err := parseTasks(list, taskOptions{
+		repackFlags: strings.Fields(repackFlags),
+		execCommand: execCommand,
+		strategy:    gcStrategy,
+		aggrEvery:   aggressiveEvery,
+		keepLargest: keepLargest,
+		bigPackSize: bigPackSize,
+		annex:       annexPol,
+		pruneGone:   pruneGoneMode,
+		unmerged:    pruneUnmerged,
+		looseExpire: pruneLoose,
+		backups:     backups{dir: backupDir, retention: backupRetention},
+		state:       st,
+		timeouts:    taskTimeouts,
+	})
+	if err != nil {
+		fmt.Println("Error parsing tasks:", err)
+		os.Exit(exitError)
+	}
+
+	// --changed-since-last-run compares repos with how they were after the
+	// last run of the same tasks
+	pipelineTasks := make([]string, len(pipeline))
+	for i, t := range pipeline {
+		pipelineTasks[i] = t.name
+	}
+
+	slices.Sort(pipelineTasks)
+
+	limits := [numTaskKinds]int{
+		kindNet:  cmp.Or(parallelNet, parallel),
+		kindDisk: cmp.Or(parallelDisk, parallel),
+	}
 
-		// If *all* directories have finished, we’re done
-		if m.index >= len(m.directories) {
-			m.done = true
-			return m, tea.Batch(progressCmd, checkMarkCmd, tea.Quit)
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		fmt.Println("Error loading config:", err)
+		os.Exit(exitError)
+	}
+
+	if err := applyGitEnv(cfg.Env); err != nil {
+		fmt.Println("Error loading config:", err)
+		os.Exit(exitError)
+	}
+
+	if rootDir == "" {
+		if rootDir, err = os.UserHomeDir(); err != nil {
+			fmt.Println("Error determining user home directory:", err)
+			os.Exit(exitError)
 		}
+	}
 
-		// Otherwise, continue
-		return m, tea.Batch(progressCmd, checkMarkCmd, nextCmd)
-	case spinner.TickMsg:
-		var cmd tea.Cmd
-		m.spinner, cmd = m.spinner.Update(msg)
-		return m, cmd
-	case progress.FrameMsg:
-		newModel, cmd := m.progress.Update(msg)
-		if newProg, ok := newModel.(progress.Model); ok {
-			m.progress = newProg
+	if rootDir, err = filepath.Abs(rootDir); err != nil {
+		fmt.Println("Error resolving root:", err)
+		os.Exit(exitError)
+	}
+
+	if slices.Contains(controlCommands, command) {
+		requests := []string{command}
+		if command == "add" {
+			if requests, err = addRequests(flag.Args()); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(exitError)
+			}
 		}
-		return m, cmd
-	case tea.QuitMsg:
-		return m, tea.Quit
+
+		for _, request := range requests {
+			reply, err := sendControl(rootDir, request)
+			if err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(exitError)
+			}
+
+			fmt.Println(reply)
+		}
+
+		os.Exit(exitOK)
 	}
 
-	return m, nil
-}
+	if command == "schedule" {
+		os.Exit(runSchedule(action, rootDir, per))
+	}
 
-func (m model) View() string {
-	total := len(m.directories)
-	if m.done {
-		return m.styles.done.Render(
-			fmt.Sprintf("Done! Ran garbage collection on %d repos.\n", total),
-		)
+	if command == "list" {
+		os.Exit(listRepos(rootDir, scanOptions{timeout: scanTimeout, dirTimeout: dirTimeout}, sh, order))
 	}
 
-	var (
-		spin = m.spinner.View() + " "
-		prog = m.progress.View()
+	// Limiting git-gc's resources can leave something to clean up on exit
+	exit := os.Exit
+	if limitMemory != "" || limitCPUs > 0 {
+		var memory int64
+		if limitMemory != "" {
+			if memory, err = parseSize(limitMemory); err != nil {
+				fmt.Println("Error parsing --limit-memory:", err)
+				os.Exit(exitError)
+			}
+		}
 
-		// compute spacing based on terminal width
-		pkgCount = fmt.Sprintf(" %d/%d", m.index, total)
-		info     = lipgloss.NewStyle().
-				MaxWidth(max(0, m.width-lipgloss.Width(spin+prog+pkgCount))).
-				Render(fmt.Sprintf("Cleaning repos... %d/%d complete", m.index, total))
-	)
+		release, err := limitResources(memory, limitCPUs)
+		if err != nil {
+			fmt.Println("Error limiting resources:", err)
+			os.Exit(exitError)
+		}
 
-	return spin +
-		info +
-		strings.Repeat(" ", max(0, m.width-lipgloss.Width(spin+info+prog+pkgCount))) +
-		prog +
-		pkgCount
-}
+		exit = func(code int) {
+			release()
+			os.Exit(code)
+		}
+	}
 
-func newModel(rootDir string, concurrency int) (model, error) {
-	s := spinner.New()
-	s.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("63"))
+	if packMemory != "" {
+		limit, err := parseSize(packMemory)
+		if err == nil {
+			err = applyGitConfig(packMemoryConfig(limit))
+		}
 
-	if rootDir == "" {
-		var err error
-		rootDir, err = os.UserHomeDir()
 		if err != nil {
-			return model{}, fmt.Errorf("could not determine user home directory: %w", err)
+			fmt.Println("Error applying --pack-memory:", err)
+			exit(exitError)
 		}
 	}
 
-	dirs, err := findDirectories(rootDir)
+	h, err := newHooks(cfg.Hooks)
 	if err != nil {
-		return model{}, err
+		fmt.Println("Error loading config:", err)
+		exit(exitError)
 	}
 
-	return model{
-		directories: dirs,
-		concurrency: concurrency,
-		spinner:     s,
-		progress: progress.New(
-			progress.WithDefaultGradient(),
-			progress.WithWidth(40),
-			progress.WithoutPercentage(),
-		),
-		styles: newStyles(),
-	}, nil
-}
-
-func newStyles() styles {
-	return styles{
-		start:          lipgloss.NewStyle().Foreground(lipgloss.Color("63")),
-		checkmark:      lipgloss.NewStyle().Foreground(lipgloss.Color("42")).SetString("✓"),
-		currentDirName: lipgloss.NewStyle().Foreground(lipgloss.Color("211")),
-		done:           lipgloss.NewStyle().Margin(1, 2),
+	opener, err := parseOpener(cfg.Open)
+	if err != nil {
+		fmt.Println("Error loading config:", err)
+		exit(exitError)
 	}
-}
 
-func findDirectories(rootDir string) ([]string, error) {
-	root, err := filepath.Abs(os.ExpandEnv(rootDir))
+	devices, err := parseDeviceLimits(deviceList)
 	if err != nil {
-		return nil, fmt.Errorf("could not determine absolute path for root dir: %w", err)
+		fmt.Println("Error parsing --parallel-device:", err)
+		exit(exitError)
+	}
+
+	opts := runOptions{
+		root:     rootDir,
+		scan:     scanOptions{timeout: scanTimeout, dirTimeout: dirTimeout},
+		shard:    sh,
+		order:    order,
+		resume:   resume,
+		limits:   limits,
+		devices:  devices,
+		pipeline: pipeline,
+		hooks:    h,
+		checks: preflight{
+			shallow:     shallowPol,
+			annex:       annexPol,
+			cleanStale:  cleanStale,
+			minFree:     minFreeSize,
+			changedOnly: changedOnly,
+			tasks:       strings.Join(pipelineTasks, ","),
+			state:       st,
+		},
+		state:        st,
+		waitOnQuit:   waitOnQuit,
+		waitForLock:  waitForLock,
+		lowPriority:  lowPriority,
+		autoParallel: autoParallel,
+		whenIdle:     whenIdle,
+		onACOnly:     onACOnly,
+		repoTimeout:  repoTimeout,
+		retries:      retries,
+		rerun:        rerun,
+		failFast:     failFast,
+		maxRepos:     maxRepos,
+		maxDuration:  maxDuration,
+		stagger:      stagger,
+		spawnRate:    spawnRate,
+		spawnBurst:   spawnBurst,
+		startJitter:  startJitter,
+		hungAfter:    hungAfter,
+		verbose:      verbose,
+		pick:         !all,
+		yes:          yes,
+		dryRun:       dryRun,
+		altScreen:    altScreen,
+		title:        title,
+		compact:      compact,
+		accessible:   accessible,
+		flags:        setFlags(),
+		theme:        cfg.Theme,
+		opener:       opener,
+		logf:         func(format string, args ...any) { fmt.Printf(format, args...) },
+	}
+
+	// The UI would garble a file or pipe with escape sequences
+	if command != "daemon" && (noTUI || quiet || accessible || !isTerminal(os.Stdout)) {
+		opts.plain = log.New(os.Stdout, "", log.LstdFlags)
+		opts.logf = opts.plain.Printf
+		opts.quiet = quiet
+	}
+
+	// Stdout is left to the events or results, and there's nobody to
+	// confirm the run
+	if command != "daemon" && (porcelainOut || tapOut) {
+		if porcelainOut && tapOut {
+			fmt.Println("Error: --porcelain and --tap both write to stdout, pick one")
+			exit(exitError)
+		}
+
+		if porcelainOut {
+			opts.porcelain = newPorcelain(os.Stdout)
+		} else {
+			opts.tap = newTAPWriter(os.Stdout)
+		}
+
+		opts.plain = log.New(os.Stderr, "", log.LstdFlags)
+		opts.logf = opts.plain.Printf
+		opts.quiet, opts.yes = true, true
+	}
+
+	// Screen readers would read out the timestamps on every line
+	if command != "daemon" && accessible {
+		opts.plain.SetFlags(0)
+	}
+
+	if command == "daemon" {
+		exit(runDaemon(opts, per.every, once))
+	}
+
+	start := time.Now()
+	final, err := run(opts)
+	var running *instanceRunningError
+	if errors.As(err, &running) {
+		fmt.Printf("Error: %s, pass --wait-for-lock to wait for it\n", running)
+		exit(exitLocked)
 	}
 
-	fi, err := os.Stat(root)
 	if err != nil {
-		return nil, fmt.Errorf("could not stat root dir: %w", err)
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		exit(exitError)
 	}
 
-	if !fi.IsDir() {
-		return nil, errors.New("root dir '" + root + "' is not a directory")
+	if dryRun {
+		exit(exitOK)
 	}
 
-	dirs := hashset.New[string]()
-	if err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			if os.IsPermission(err) {
-				return nil
-			}
+	if opts.plain != nil {
+		logRun(opts.plain, final, time.Since(start))
+	}
 
-			return err
+	repos := newReport(final, opts.root, start).Repos
+	opts.tap.finish(repos)
+	if opts.porcelain != nil {
+		totals := final.totals(repos, time.Since(start))
+		opts.porcelain.emit(porcelainEvent{Event: "run-finished", Run: &totals})
+	}
+
+	if notify {
+		notifyDone(final, time.Since(start))
+	}
+
+	// Nothing ran when the user quit before the run started
+	if report != "" && !final.startAt.IsZero() {
+		if err := writeReport(report, format, final, opts.root, start); err != nil {
+			fmt.Println("Error writing the report:", err)
+		} else if openReport {
+			path, _ := filepath.Abs(report)
+			if err := openerCommand(path).Run(); err != nil {
+				fmt.Println("Error opening the report:", err)
+			}
 		}
+	}
+
+	exit(final.exitCode())
+}
+
+// setFlags returns the flags given on the command line, as --name=value.
+func setFlags() []string {
+	var flags []string
+	flag.Visit(func(f *flag.Flag) {
+		flags = append(flags, fmt.Sprintf("--%s=%s", f.Name, f.Value))
+	})
+
+	return flags
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	_, _ = fmt.Fprintf(out, "Usage:\n  git-gc [flags]         run git gc in every repo under --root\n")
+	_, _ = fmt.Fprintf(out, "  git-gc verify [flags]  run git fsck in every repo under --root\n")
+	_, _ = fmt.Fprintf(out, "  git-gc exec [flags] COMMAND\n                         run a shell command in every repo under --root\n")
+	_, _ = fmt.Fprintf(out, "  git-gc list [flags]    print the repos under --root, one per line\n")
+	_, _ = fmt.Fprintf(out, "  git-gc daemon [flags]  stay resident and run git gc under --root every --every\n")
+	_, _ = fmt.Fprintf(out, "  git-gc status|run-now|pause|unpause [--root DIR]\n                         inspect or control the daemon running in --root\n")
+	_, _ = fmt.Fprintf(out, "  git-gc add [--root DIR] PATH...\n                         add roots or repos to the daemon's run in progress\n")
+	_, _ = fmt.Fprintf(out, "  git-gc schedule install|uninstall [flags]\n                         have the system run git gc under --root every --every\n\nFlags:\n")
+	flag.PrintDefaults()
+}
+
+// defaultTaskList resolves the --tasks flag against the subcommand and the
+// --repack and --exec shorthands.
+func defaultTaskList(taskList, command string, repack, exec bool) string {
+	switch {
+	case taskList == "" && command == "verify":
+		return "fsck"
+	case taskList == "" && (command == "exec" || exec):
+		return "exec"
+	case taskList == "":
+		taskList = "gc"
+	case exec && !slices.Contains(strings.Split(taskList, ","), "exec"):
+		taskList += ",exec"
+	}
 
-		if info.IsDir() && !strings.HasPrefix(info.Name(), ".") {
-			if _, err := os.Stat(filepath.Join(path, ".git")); err == nil {
-				dirs.Add(path)
+	if repack {
+		names := strings.Split(taskList, ",")
+		for i, name := range names {
+			if strings.TrimSpace(name) == "gc" {
+				names[i] = "repack"
 			}
 		}
 
-		return nil
-	}); err != nil {
-		return nil, err
+		taskList = strings.Join(names, ",")
+	}
+
+	return taskList
+}
+
+// withPruneGone makes sure the task list fetches (and so prunes) before
+// deleting gone branches.
+func withPruneGone(taskList string) string {
+	names := strings.Split(taskList, ",")
+	if !slices.Contains(names, "prune-gone") {
+		names = append(names, "prune-gone")
+	}
+
+	if i := slices.Index(names, "fetch"); i < 0 || i > slices.Index(names, "prune-gone") {
+		names = slices.Insert(names, slices.Index(names, "prune-gone"), "fetch")
+	}
+
+	return strings.Join(names, ",")
+}
+
+func (m model) Init() tea.Cmd {
+	spinnerCmd := m.spinner.Tick
+
+	// If no directories at all, just quit
+	if len(m.directories) == 0 {
+		return tea.Sequence(
+			spinnerCmd,
+			tea.Quit,
+		)
+	}
+
+	var loadCmd tea.Cmd
+	switch {
+	case m.whenIdle || m.onACOnly:
+		// Nothing starts before the first sample says it's fine
+		loadCmd = m.load.sampleAfter(0)
+	case m.load != nil:
+		loadCmd = m.load.next()
+	}
+
+	var spawnCmd tea.Cmd
+	if m.stagger > 0 || m.spawnRate != nil || time.Now().Before(m.startAt) {
+		spawnCmd = spawnAfter(time.Until(m.startAt))
+	}
+
+	var watchdogCmd tea.Cmd
+	if m.watchdog.after > 0 {
+		watchdogCmd = m.watchdog.next(m.runner)
+	}
+
+	// Without the UI there's no progress bar to weight
+	var weightsCmd tea.Cmd
+	if m.plain == nil {
+		weightsCmd = measureWeights(m.directories)
+	}
+
+	var budgetCmd tea.Cmd
+	if !m.budget.deadline.IsZero() {
+		budgetCmd = tea.Tick(time.Until(m.budget.deadline), func(time.Time) tea.Msg { return budgetExpired{} })
+	}
+
+	// Spawning happens in Update so the scheduling state sticks to the model
+	var statusCmd tea.Cmd
+	if m.accessible {
+		statusCmd = nextStatus()
 	}
 
-	dirsSlice := dirs.Values()
-	slices.Sort(dirsSlice)
-	return dirsSlice, nil
+	return tea.Batch(
+		spinnerCmd,
+		statusCmd,
+		m.windowTitle(),
+		loadCmd,
+		budgetCmd,
+		spawnCmd,
+		watchdogCmd,
+		weightsCmd,
+		m.runner.next(),
+		func() tea.Msg { return runStarted{} },
+	)
 }
 
-func runGitGC(dir string) tea.Cmd {
-	cmd := exec.Command("git", "-C", dir, "gc")
-	cmd.Stdout = io.Discard
-	cmd.Stderr = io.Discard
+func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.progress.Width = barWidth(m.width)
+		m.shown = nil
+		m.syncHistory()
+		return m, nil
+	case tea.KeyMsg:
+		if m.offerRetry {
+			return m.answerRetry(msg)
+		}
+
+		if m.adding {
+			return m.typePath(msg)
+		}
 
-	return tea.ExecProcess(cmd, func(exitErr error) tea.Msg {
-		if exitErr != nil {
-			return tea.Quit
+		if m.filtering {
+			return m.typeFilter(msg)
 		}
 
-		return dirGitGCCompleted(dir)
-	})
+		if m.confirmQuit {
+			m.confirmQuit = false
+			switch msg.String() {
+			case "y", "Y", "q", "Q", "ctrl+c":
+				return m, m.quit()
+			}
+
+			return m, nil
+		}
+
+		if m.showHelp {
+			switch msg.String() {
+			case "?", "esc", "q":
+				m.showHelp = false
+				return m, nil
+			}
+		}
+
+		switch msg.String() {
+		case "?":
+			m.showHelp = true
+			return m, nil
+		case "esc":
+			if m.result != "" {
+				m.result = ""
+				return m, nil
+			}
+
+			if m.filter != "" {
+				m.setFilter("")
+				return m, nil
+			}
+
+			return m, m.askQuit()
+		case "q":
+			return m, m.askQuit()
+		case "ctrl+c", "Q":
+			return m, m.quit()
+		case "/":
+			m.filtering = true
+			return m, nil
+		case "p", "P":
+			if !m.quitting {
+				m.setPaused(!m.paused, msg.String() == "P")
+				return m, nil
+			}
+		case "c":
+			return m, m.copyResult()
+		case "y", "Y":
+			if len(m.confirms) > 0 {
+				c := m.confirms[0]
+				m.confirms = m.confirms[1:]
+				c.inv.confirm = ""
+				c.job.inv = &c.inv
+				m.enqueue(c.job)
+				m.dispatch()
+				return m, nil
+			}
+		case "n", "N":
+			if len(m.confirms) > 0 {
+				c := m.confirms[0]
+				m.confirms = m.confirms[1:]
+				return m, m.taskDone(taskCompleted{dir: c.job.dir, step: c.job.step, note: "declined: " + c.inv.note})
+			}
+		case "a":
+			if !m.quitting {
+				m.adding = true
+				return m, nil
+			}
+		case "k":
+			if len(m.hung) > 0 {
+				m.runner.killHung(m.hung[0].dir)
+				m.hung = m.hung[1:]
+				return m, nil
+			}
+		case "s":
+			// Only what the user picked, never the oldest repo in its stead
+			if _, ok := m.started[m.selected]; ok {
+				return m, m.skipRepo(m.selected)
+			}
+		case "d":
+			m.detail = !m.detail
+			return m, nil
+		case "g":
+			m.grouped, m.scrolled = !m.grouped, false
+			m.syncHistory()
+			return m, nil
+		case "S":
+			m.order, m.scrolled = (m.order+1)%numHistoryOrders, false
+			m.syncHistory()
+			return m, nil
+		case "f":
+			m.altScreen = !m.altScreen
+			if m.altScreen {
+				return m, tea.EnterAltScreen
+			}
+
+			return m, tea.ExitAltScreen
+		case "tab":
+			m.selectNext(1)
+			return m, nil
+		case "shift+tab":
+			m.selectNext(-1)
+			return m, nil
+		case "pgup", "pgdown", "home", "end":
+			m.scrollHistory(msg.String())
+			return m, nil
+		case "up":
+			m.selectResult(-1)
+			return m, nil
+		case "down":
+			m.selectResult(1)
+			return m, nil
+		case "o":
+			return m, m.openResult()
+		case "+", "=":
+			m.adjustParallelism(1)
+			return m, nil
+		case "-", "_":
+			m.adjustParallelism(-1)
+			return m, nil
+		}
+	case runStarted:
+		// Queue the first task of every repo and start as many as the pools allow
+		for _, dir := range m.directories {
+			m.enqueue(job{dir: dir})
+		}
+
+		m.dispatch()
+		return m, nil
+	case repoWeights:
+		m.weights = msg
+		return m, m.progress.SetPercent(m.percent())
+	case pauseSet:
+		m.setPaused(msg.paused, false)
+		return m, nil
+	case statusRequest:
+		msg.reply <- m.status()
+		return m, nil
+	case outputLine:
+		return m, m.printRepo(msg.dir, m.styles.note.Render(msg.dir+": "+msg.line))
+	case repoOpened:
+		if msg.err != nil {
+			return m, m.println(m.styles.note.Render(tr("Could not open %s: %s", msg.dir, msg.err)))
+		}
+
+		return m, nil
+	case addRequest:
+		return m, m.findAdded(msg.path)
+	case reposFound:
+		if m.quitting || m.done {
+			return m, nil
+		}
+
+		return m, m.addRepos(msg)
+	case hungChecked:
+		// Suspended tasks are expected to do nothing
+		m.hung = msg.hung
+		if m.frozen {
+			m.hung = nil
+		}
+
+		return m, m.watchdog.next(m.runner)
+	case spawnDue:
+		m.dispatch()
+		if wait, ok := m.nextSpawnDue(); ok {
+			return m, spawnAfter(wait)
+		}
+
+		return m, nil
+	case statusDue:
+		if m.done {
+			return m, nil
+		}
+
+		m.plain.Print(m.progressLine())
+		return m, nextStatus()
+	case budgetExpired:
+		if m.quitting {
+			return m, nil
+		}
+
+		m.leaveRemaining()
+		return m, m.quitIfOutOfBudget()
+	case confirmRequest:
+		// Don't hold on to a pool slot while waiting for the user
+		m.running--
+		m.release(msg.job.dir, msg.job.step)
+		if m.quitting {
+			return m, tea.Batch(m.runner.next(), m.quitIfIdle())
+		}
+
+		if m.unattended {
+			done := taskCompleted{dir: msg.job.dir, step: msg.job.step, note: "declined, nobody to ask: " + msg.inv.note}
+			return m, tea.Batch(m.runner.next(), m.taskDone(done))
+		}
+
+		m.confirms = append(m.confirms, msg)
+		m.dispatch()
+		return m, m.runner.next()
+	case loadSampled:
+		if msg.err != nil {
+			// Without the load there's nothing to scale or hold off by
+			m.autoParallel, m.whenIdle, m.idleWait = false, false, ""
+			m.onACOnly, m.powerWait = false, ""
+			m.dispatch()
+			return m, nil
+		}
+
+		if m.autoParallel {
+			p := &m.pools[kindDisk]
+			p.limit = min(max(1, p.limit+m.load.adjust(msg.sample)), m.maxParallel)
+		}
+
+		if m.whenIdle {
+			m.idleWait = m.load.busy(msg.sample, m.running)
+		}
+
+		if m.onACOnly {
+			m.powerWait = ""
+			if msg.sample.power != "" {
+				m.powerWait = tr("Waiting while the machine is %s", tr(msg.sample.power))
+			}
+		}
+
+		m.dispatch()
+		return m, m.load.next()
+	case retryDue:
+		if m.quitting {
+			return m, m.taskDone(taskCompleted{dir: msg.job.dir, step: msg.job.step, err: errInterrupted})
+		}
+
+		m.enqueue(msg.job)
+		m.dispatch()
+		return m, nil
+	case taskCompleted:
+		m.running--
+		m.release(msg.dir, msg.step)
+		m.hung = slices.DeleteFunc(m.hung, func(h hungTask) bool { return h.dir == msg.dir })
+		return m, tea.Batch(m.runner.next(), m.taskDone(msg), m.quitIfIdle())
+	case postHookCompleted:
+		m.running--
+		status := statusSucceeded
+		if m.failed(msg.dir) {
+			status = statusFailed
+		} else if msg.err != nil {
+			m.fail(repoFailure{dir: msg.dir, task: "post", err: msg.err})
+			status = statusFailed
+		}
+
+		return m, tea.Batch(m.runner.next(), m.finishRepo(msg.dir, status), m.quitIfIdle())
+	case spinner.TickMsg:
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+	case clipboardWritten:
+		if msg.id == m.clipboard.id {
+			m.clipboard.seq = ""
+		}
+		return m, nil
+	case progress.FrameMsg:
+		newModel, cmd := m.progress.Update(msg)
+		if newProg, ok := newModel.(progress.Model); ok {
+			m.progress = newProg
+		}
+		return m, cmd
+	case signalled:
+		// Stop the running tasks right away, and kill them on the next one
+		m.quitting = true
+		return m, m.quit()
+	}
+
+	return m, nil
+}
+
+// taskDone moves a repo along once a step of its pipeline is over: on to its
+// next task, or to its post hook and completion.
+func (m *model) taskDone(msg taskCompleted) tea.Cmd {
+	if msg.sizeBefore > 0 {
+		m.sizes[msg.dir] = msg.sizeBefore
+	}
+
+	if msg.err != nil && msg.transient && !m.quitting && m.attempts[msg.dir] < m.retries {
+		m.attempts[msg.dir]++
+		j := job{dir: msg.dir, step: msg.step}
+		return tea.Tick(retryBackoff(m.attempts[msg.dir]), func(time.Time) tea.Msg { return retryDue{job: j} })
+	}
+
+	if n := m.attempts[msg.dir]; n > 0 {
+		if msg.err == nil {
+			msg.note = joinNotes(msg.note, fmt.Sprintf("%s succeeded on attempt %d", m.pipeline[msg.step].name, n+1))
+		} else {
+			msg.err = fmt.Errorf("%w (after %d attempts)", msg.err, n+1)
+		}
+
+		delete(m.attempts, msg.dir)
+	}
+
+	if msg.note != "" {
+		m.notes[msg.dir] = append(m.notes[msg.dir], msg.note)
+	}
+
+	if before, ok := m.sizes[msg.dir]; ok && msg.sizeAfter > 0 {
+		m.freed[msg.dir] = before - msg.sizeAfter
+	}
+
+	if msg.skip != "" {
+		m.skipped = append(m.skipped, repoSkip{dir: msg.dir, reason: msg.skip})
+		m.dispatch()
+		return m.finishRepo(msg.dir, statusSkipped)
+	}
+
+	// A repo that's interrupted between two tasks isn't done either
+	if m.quitting && msg.err == nil && msg.step+1 < len(m.pipeline) {
+		msg.step, msg.err = msg.step+1, errInterrupted
+	}
+
+	// Move on to the next task of this repo's pipeline, unless this one failed
+	if msg.err == nil && msg.step+1 < len(m.pipeline) {
+		m.enqueue(job{dir: msg.dir, step: msg.step + 1})
+		m.dispatch()
+		return nil
+	}
+
+	if msg.err != nil {
+		m.fail(repoFailure{
+			dir:    msg.dir,
+			task:   m.pipeline[msg.step].name,
+			err:    msg.err,
+			output: msg.output,
+		})
+	}
+
+	// The repo's slot may have been the one something was waiting for
+	m.dispatch()
+
+	if m.hooks.post != nil && !m.quitting {
+		m.current[msg.dir] = "post hook"
+		elapsed := time.Since(m.started[msg.dir])
+		m.start(runPostHook(m.hooks.post, msg.dir, elapsed, msg.err))
+		return nil
+	}
+
+	status := statusSucceeded
+	if msg.err != nil {
+		status = statusFailed
+	}
+
+	return m.finishRepo(msg.dir, status)
+}
+
+// finishRepo records that every task of dir's pipeline (and its hooks) ran.
+func (m *model) finishRepo(dir string, status repoStatus) tea.Cmd {
+	m.index++
+	if !m.interrupted(dir) {
+		m.journal.finished(dir)
+	}
+
+	m.lastDone = time.Now()
+	notes := m.notes[dir]
+	if start, ok := m.started[dir]; ok {
+		m.took[dir] = m.lastDone.Sub(start)
+		notes = append([]string{formatTook(m.took[dir])}, notes...)
+		if status == statusSucceeded {
+			m.journal.recordTook(dir, m.took[dir])
+		}
+	}
+
+	freed, measured := m.freed[dir]
+	m.reclaim += freed
+	result := repoResult{status: status, freed: freed, before: m.sizes[dir]}
+	if measured {
+		result.after = result.before - freed
+	}
+	delete(m.started, dir)
+	delete(m.current, dir)
+	m.runner.forget(dir)
+	delete(m.notes, dir)
+	delete(m.sizes, dir)
+	delete(m.freed, dir)
+
+	// Update our progress bar
+	m.finished[dir] = true
+	m.results[dir] = result
+	if m.porcelain != nil || m.tap != nil {
+		repo := m.repoReport(dir)
+		m.porcelain.emit(porcelainEvent{Event: "repo-finished", Path: dir, Repo: &repo})
+		m.tap.result(repo)
+	}
+	progressCmd := m.progress.SetPercent(m.percent())
+	// Print checkmark for the completed directory
+	var checkMarkCmd tea.Cmd
+	switch status {
+	case statusSucceeded:
+		line := fmt.Sprintf("%s %s", m.styles.checkmark, dir)
+		switch {
+		case freed > 0:
+			line += " freed " + formatSize(freed)
+		case freed < 0:
+			line += " grew " + formatSize(-freed)
+		}
+
+		if len(notes) > 0 {
+			line += " " + m.styles.note.Render("("+strings.Join(notes, "; ")+")")
+		}
+
+		checkMarkCmd = m.printRepo(dir, line)
+	case statusFailed:
+		checkMarkCmd = m.printRepo(dir, m.failureLine(dir, notes))
+	case statusSkipped:
+		reason := m.skipped[len(m.skipped)-1].reason
+		checkMarkCmd = m.printRepo(dir, m.styles.note.Render(fmt.Sprintf("- %s (skipped: %s)", dir, reason)))
+	}
+
+	// If *all* directories have finished, we’re done
+	if m.index+len(m.remaining) >= len(m.directories) && !m.rerunFailed() {
+		m.done = true
+		if m.offerRetry = m.retryable(); m.offerRetry {
+			return tea.Batch(progressCmd, m.windowTitle(), checkMarkCmd)
+		}
+
+		return tea.Batch(progressCmd, m.windowTitle(), checkMarkCmd, m.exit())
+	}
+
+	return tea.Batch(progressCmd, m.windowTitle(), checkMarkCmd)
+}
+
+// failureLine renders the completion line of a repo that failed, followed
+// by the last lines its failed task wrote to stderr.
+func (m model) failureLine(dir string, notes []string) string {
+	var f repoFailure
+	for _, failure := range slices.Backward(m.failures) {
+		if failure.dir == dir {
+			f = failure
+			break
+		}
+	}
+
+	notes = append(notes, fmt.Sprintf("%s: %s", f.task, f.err))
+	lines := []string{fmt.Sprintf("%s %s %s", m.styles.cross, dir, m.styles.note.Render("("+strings.Join(notes, "; ")+")"))}
+	for _, line := range f.output {
+		lines = append(lines, m.styles.note.Render("    "+line))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// formatTook rounds how long a repo took for its completion line.
+func formatTook(d time.Duration) string {
+	switch {
+	case d < time.Second:
+		return d.Round(time.Millisecond).String()
+	case d < 10*time.Second:
+		return d.Round(100 * time.Millisecond).String()
+	}
+
+	return d.Round(time.Second).String()
+}
+
+// rerunFailed starts a second pass over the repos that failed, since many
+// failures come from the user touching a repo mid-run. It reports whether
+// there was anything to run again.
+func (m *model) rerunFailed() bool {
+	if !m.rerun || m.reran != nil || m.quitting || m.remaining != nil {
+		return false
+	}
+
+	return m.requeueFailed()
+}
+
+// requeueFailed queues the repos that failed for reasons other than the
+// user quitting to run again. It reports whether there were any.
+func (m *model) requeueFailed() bool {
+	dirs := m.failedDirs()
+	if len(dirs) == 0 {
+		return false
+	}
+
+	m.reran = dirs
+	m.failures = slices.DeleteFunc(m.failures, func(f repoFailure) bool { return slices.Contains(dirs, f.dir) })
+	m.index -= len(dirs)
+	for _, dir := range dirs {
+		delete(m.finished, dir)
+		delete(m.results, dir)
+		m.notes[dir] = []string{"second pass"}
+		m.enqueue(job{dir: dir})
+	}
+
+	m.dispatch()
+	return true
+}
+
+func (m model) exitCode() int {
+	if slices.ContainsFunc(m.failures, func(f repoFailure) bool { return !errors.Is(f.err, errInterrupted) }) {
+		return exitFailed
+	}
+
+	if m.quitting {
+		return exitStopped
+	}
+
+	return exitOK
+}
+
+// failedDirs returns the repos that failed for reasons other than the user
+// quitting.
+func (m model) failedDirs() []string {
+	var dirs []string
+	for _, f := range m.failures {
+		if !errors.Is(f.err, errInterrupted) && !slices.Contains(dirs, f.dir) {
+			dirs = append(dirs, f.dir)
+		}
+	}
+
+	return dirs
+}
+
+// interrupted reports whether dir failed because the user quit.
+func (m model) interrupted(dir string) bool {
+	return slices.ContainsFunc(m.failures, func(f repoFailure) bool {
+		return f.dir == dir && errors.Is(f.err, errInterrupted)
+	})
+}
+
+func (m model) failed(dir string) bool {
+	return slices.ContainsFunc(m.failures, func(f repoFailure) bool { return f.dir == dir })
+}
+
+// enqueue queues a job in the pool for the kind of its task.
+func (m *model) enqueue(j job) {
+	p := &m.pools[m.pipeline[j.step].kind]
+	p.queue = append(p.queue, j)
+}
+
+// start runs w on the runner, keeping track of it until it reports back.
+func (m *model) start(w work) {
+	m.running++
+	m.runner.start(w)
+}
+
+// quit stops starting new tasks, and asks the running ones to stop unless
+// the user wants to wait for them. Asking twice stops them either way, and
+// asking once they're stopping kills them.
+func (m *model) quit() tea.Cmd {
+	// Suspended tasks couldn't stop or finish
+	m.setPaused(m.paused, false)
+	if m.stopped {
+		m.runner.kill()
+		m.killed = true
+	} else if m.quitting || !m.waitOnQuit {
+		m.stop()
+	}
+
+	m.quitting = true
+	m.confirms = nil
+	return m.quitIfIdle()
+}
+
+// askQuit quits once the user confirmed it, so that a stray q doesn't end
+// the run. Once quitting, q goes on to stop or kill the running tasks
+// right away.
+func (m *model) askQuit() tea.Cmd {
+	if m.quitting || m.done {
+		return m.quit()
+	}
+
+	m.confirmQuit = true
+	return nil
+}
+
+// stop stops starting new tasks and asks the running ones to stop.
+func (m *model) stop() {
+	m.runner.stop()
+	m.stopped = true
+	m.quitting = true
+	m.confirms = nil
+}
+
+// fail records a failed repo, stopping the run if that's the first failure
+// and failFast is set.
+func (m *model) fail(f repoFailure) {
+	m.failures = append(m.failures, f)
+	if m.failFast && !m.quitting {
+		m.failedFast = true
+		m.stop()
+	}
+}
+
+// quitIfOutOfBudget ends the program once the budget ran out while nothing
+// was running.
+func (m *model) quitIfOutOfBudget() tea.Cmd {
+	if m.remaining == nil || m.index+len(m.remaining) < len(m.directories) {
+		return nil
+	}
+
+	m.done = true
+	return m.exit()
+}
+
+// quitIfIdle ends the program once nothing is running after the user quit.
+func (m *model) quitIfIdle() tea.Cmd {
+	if !m.quitting || m.running > 0 {
+		return nil
+	}
+
+	m.done = true
+	return m.exit()
+}
+
+// setPaused pauses or resumes starting new tasks. With freeze, pausing also
+// suspends the running tasks where that's supported; anything else
+// continues them.
+func (m *model) setPaused(paused, freeze bool) {
+	if m.frozen && (!paused || !freeze) {
+		m.runner.resume()
+		m.frozen = false
+	}
+
+	if paused && freeze && !m.frozen {
+		m.frozen = m.runner.suspend()
+	}
+
+	m.paused = paused
+	m.dispatch()
+}
+
+// adjustParallelism changes the limit of every pool by delta, never going
+// below one. Lowering it lets the running tasks finish rather than stopping
+// them.
+func (m *model) adjustParallelism(delta int) {
+	m.autoParallel = false
+	for k := range m.pools {
+		m.pools[k].limit = max(1, m.pools[k].limit+delta)
+	}
+
+	m.dispatch()
+}
+
+// status describes the run in progress in one line.
+func (m model) status() string {
+	s := tr("running, %d/%d repos complete, %d tasks running, %s", m.index, len(m.directories), m.running, m.parallelism())
+	if held := m.held(); held != "" {
+		// It's a sentence of its own elsewhere
+		r, size := utf8.DecodeRuneInString(held)
+		s += tr(", %s", string(unicode.ToLower(r))+held[size:])
+	}
+
+	return s
+}
+
+// parallelism describes the pool limits for the status line.
+func (m model) parallelism() string {
+	net, disk := m.pools[kindNet].limit, m.pools[kindDisk].limit
+	s := tr("%d/%d parallel net/disk", net, disk)
+	if net == disk {
+		s = tr("%d parallel", disk)
+	}
+
+	if m.autoParallel {
+		s += tr(" (auto)")
+	}
+
+	return s
+}
+
+// dispatch starts queued jobs while their pools have free slots.
+func (m *model) dispatch() {
+	if m.quitting || m.held() != "" {
+		return
+	}
+
+	if m.budget.exhausted(m.begun, time.Now()) {
+		m.leaveRemaining()
+	}
+
+	for k := range m.pools {
+		p := &m.pools[k]
+		for i := 0; p.inFlight < p.limit && i < len(p.queue); {
+			j := p.queue[i]
+			first := j.step == 0 && j.inv == nil && m.attempts[j.dir] == 0
+
+			// Only as many repos start as the budget allows
+			if first && m.budget.exhausted(m.begun, time.Now()) {
+				i++
+				continue
+			}
+
+			// Jobs on a busy device wait without holding up the others
+			if taskKind(k) == kindDisk && !m.devices.hasRoom(j.dir) {
+				i++
+				continue
+			}
+
+			// Tasks start no faster than --stagger and --spawn-rate allow
+			now := time.Now()
+			if now.Before(m.nextSpawn) || (m.spawnRate != nil && !m.spawnRate.take(now)) {
+				return
+			}
+
+			if m.stagger > 0 {
+				m.nextSpawn = now.Add(m.stagger)
+			}
+
+			p.queue = slices.Delete(p.queue, i, i+1)
+			p.inFlight++
+			if taskKind(k) == kindDisk {
+				m.devices.acquire(j.dir)
+			}
+
+			if first {
+				m.started[j.dir] = time.Now()
+				m.begun++
+				m.porcelain.emit(porcelainEvent{Event: "repo-started", Path: j.dir})
+			}
+
+			m.current[j.dir] = m.pipeline[j.step].name
+
+			m.start(m.runTask(j))
+		}
+	}
+}
+
+// nextSpawnDue returns when to check for tasks to start again, if tasks are
+// held off by time at all.
+func (m model) nextSpawnDue() (time.Duration, bool) {
+	now := time.Now()
+	if now.Before(m.startAt) {
+		return m.startAt.Sub(now), true
+	}
+
+	if m.stagger <= 0 && m.spawnRate == nil {
+		return 0, false
+	}
+
+	wait := m.nextSpawn.Sub(now)
+	if m.spawnRate != nil {
+		wait = max(wait, m.spawnRate.wait(now))
+	}
+
+	// Nothing's waiting to start right now, so keep checking at the pace
+	// tasks may start
+	if wait <= 0 {
+		wait = m.stagger
+		if m.spawnRate != nil {
+			wait = max(wait, time.Duration(float64(time.Second)/m.spawnRate.rate))
+		}
+	}
+
+	return wait, true
+}
+
+// leaveRemaining takes the repos that haven't started yet off the queues,
+// leaving them for the next run.
+func (m *model) leaveRemaining() {
+	if m.remaining == nil {
+		m.remaining = []string{}
+	}
+
+	for k := range m.pools {
+		m.pools[k].queue = slices.DeleteFunc(m.pools[k].queue, func(j job) bool {
+			if j.step != 0 || j.inv != nil || m.attempts[j.dir] != 0 {
+				return false
+			}
+
+			m.remaining = append(m.remaining, j.dir)
+			return true
+		})
+	}
+}
+
+// held returns why no new tasks start right now, or "" if they do.
+func (m model) held() string {
+	if m.paused {
+		return tr("Paused")
+	}
+
+	if wait := time.Until(m.startAt); wait > 0 {
+		return tr("Starting in %s", wait.Truncate(time.Second)+time.Second)
+	}
+
+	if m.powerWait != "" {
+		return m.powerWait
+	}
+
+	return m.idleWait
+}
+
+// release frees the slots a step of dir's pipeline held.
+func (m *model) release(dir string, step int) {
+	kind := m.pipeline[step].kind
+	m.pools[kind].inFlight--
+	if kind == kindDisk {
+		m.devices.release(dir)
+	}
+}
+
+func (m model) View() string {
+	return m.clipboard.seq + m.view()
+}
+
+func (m model) view() string {
+	if m.done {
+		return m.styles.done.Render(m.summary() + m.retryPrompt())
+	}
+
+	footer := m.footer()
+	if m.showHelp {
+		footer = m.helpOverlay() + footer
+	}
+
+	if height := m.historyHeight(footer); height > 0 {
+		history := m.history
+		history.Height = height
+		if !m.scrolled {
+			history.GotoBottom()
+		}
+
+		return history.View() + "\n" + footer
+	}
+
+	return footer
+}
+
+// footer renders what's below the history: the running repos, a prompt or
+// what the run waits for, and the progress bar.
+func (m model) footer() string {
+	total := len(m.directories)
+
+	var prompt string
+	switch {
+	case m.killed:
+		prompt = m.styles.note.Render(tr("Killing %d running tasks...", m.running)) + "\n"
+	case m.stopped:
+		prompt = m.styles.note.Render(tr(
+			"Stopping %d running tasks, press q again to kill them...", m.running,
+		)) + "\n"
+	case m.quitting:
+		prompt = m.styles.note.Render(tr(
+			"Waiting for %d running tasks to finish, press q again to stop them...", m.running,
+		)) + "\n"
+	case m.confirmQuit:
+		what := tr("Running tasks will be stopped.")
+		if m.waitOnQuit {
+			what = tr("Running tasks will finish first.")
+		}
+
+		prompt = m.styles.currentDirName.Render(tr("Abort the run? ")) + m.styles.note.Render(what) + " [y/N]" + "\n"
+	case m.adding:
+		prompt = m.styles.currentDirName.Render(tr("Add a root or repo: ")) + m.addInput + "█" +
+			m.styles.note.Render(tr(" (enter to add, esc to cancel)")) + "\n"
+	case m.filtering:
+		prompt = m.styles.currentDirName.Render(tr("Filter: ")) + m.filter + "█" +
+			m.styles.note.Render(tr(" (enter to keep, esc to clear)")) + "\n"
+	case len(m.confirms) > 0:
+		prompt = m.styles.currentDirName.Render(m.confirms[0].inv.confirm) + " [y/N]"
+		if n := len(m.confirms) - 1; n > 0 {
+			prompt += m.styles.note.Render(tr(" (%d more waiting)", n))
+		}
+
+		prompt += "\n"
+	case len(m.hung) > 0:
+		h := m.hung[0]
+		prompt = m.styles.currentDirName.Render(tr(
+			"%s looks hung, nothing happened for %s", h.dir, h.idle.Round(time.Second),
+		)) + tr(", press k to kill it and skip the repo")
+		if n := len(m.hung) - 1; n > 0 {
+			prompt += m.styles.note.Render(tr(" (%d more)", n))
+		}
+
+		prompt += "\n"
+	case m.paused && !m.quitting:
+		prompt = m.styles.paused.Render(" PAUSED ") + m.styles.note.Render(tr(" No new tasks start, press p to resume")) + "\n"
+		if m.frozen {
+			prompt = m.styles.paused.Render(" PAUSED ") + m.styles.note.Render(tr(" The running tasks are suspended, press p to resume")) + "\n"
+		}
+	case m.held() != "":
+		prompt = m.styles.note.Render(m.held()+"...") + "\n"
+	case m.result != "":
+		prompt = m.styles.currentDirName.Render(m.result) +
+			m.styles.note.Render(tr(" selected, o to open it, c to copy it, up/down for another, esc to unselect")) + "\n"
+	case m.filter != "":
+		prompt = m.styles.note.Render(tr("Showing the repos matching %q, / to change, esc to clear", m.filter)) + "\n"
+	case m.order != orderFinished:
+		prompt = m.styles.note.Render(tr("Sorted by %s, S to change", tr(m.order.String()))) + "\n"
+	}
+
+	// Prompts wrap, so that the UI knows how many lines they take
+	if prompt != "" && m.width > 0 {
+		prompt = lipgloss.NewStyle().Width(m.width).Render(strings.TrimSuffix(prompt, "\n")) + "\n"
+	}
+
+	spin := m.spinner.View() + " "
+	if m.narrow() {
+		return m.detailPane() +
+			m.lanes() +
+			prompt +
+			fit(fmt.Sprintf("%s%d/%d, %.0f%%", spin, m.index, total, 100*m.percent()), m.width)
+	}
+
+	var (
+		prog = m.progress.View()
+
+		// compute spacing based on terminal width
+		pkgCount = fmt.Sprintf(" %d/%d", m.index, total)
+		status   = tr("Cleaning %s %d/%d complete, %s", m.runningNames(), m.index, total, m.parallelism())
+	)
+
+	if elapsed, rate, ok := m.throughput(); ok {
+		status += tr(", %s elapsed", elapsed.Round(time.Second))
+		if m.index > 0 {
+			status += tr(", %.1f repos/min", rate)
+		}
+	}
+
+	if left, ok := m.eta(); ok {
+		status += tr(", ~%s left", formatTook(left))
+	}
+
+	info := status
+	if m.width > 0 {
+		info = fit(status, max(0, m.width-lipgloss.Width(spin+prog+pkgCount)-1))
+	}
+
+	return m.detailPane() +
+		m.lanes() +
+		prompt +
+		spin +
+		info +
+		strings.Repeat(" ", max(0, m.width-lipgloss.Width(spin+info+prog+pkgCount))) +
+		prog +
+		pkgCount
+}
+
+func (m model) action() string {
+	descs := make([]string, len(m.pipeline))
+	for i, t := range m.pipeline {
+		descs[i] = tr(t.desc)
+	}
+
+	return strings.Join(descs, ", ")
+}
+
+// failureLabel is the heading of the list of failures in the summary, a
+// format taking their count.
+func (m model) failureLabel() string {
+	if len(m.pipeline) == 1 && m.pipeline[0].name == "fsck" {
+		return "%d corrupt repos:"
+	}
+
+	return "%d failed repos:"
+}
+
+func newModel(dirs []string, limits [numTaskKinds]int, pipeline []task, h hooks, checks preflight) model {
+	st := newStyles(defaultTheme)
+	s := spinner.New()
+	s.Style = st.start
+
+	m := model{
+		directories: dirs,
+		pipeline:    pipeline,
+		hooks:       h,
+		checks:      checks,
+		started:     make(map[string]time.Time),
+		current:     make(map[string]string),
+		notes:       make(map[string][]string),
+		sizes:       make(map[string]int64),
+		freed:       make(map[string]int64),
+		took:        make(map[string]time.Duration),
+		finished:    make(map[string]bool),
+		results:     make(map[string]repoResult),
+		attempts:    make(map[string]int),
+		runner:      newRunner(context.Background()),
+		spinner:     s,
+		progress: progress.New(
+			progress.WithColorProfile(lipgloss.ColorProfile()),
+			progress.WithDefaultGradient(),
+			progress.WithWidth(40),
+			progress.WithoutPercentage(),
+		),
+		laneBar: progress.New(
+			progress.WithColorProfile(lipgloss.ColorProfile()),
+			progress.WithDefaultGradient(),
+			progress.WithWidth(laneBarWidth),
+			progress.WithoutPercentage(),
+		),
+		styles: st,
+	}
+
+	for k, limit := range limits {
+		m.pools[k].limit = max(1, limit)
+	}
+
+	return m
 }
@@ -0,0 +1,33 @@
+package main
+
+import "github.com/charmbracelet/x/ansi"
+
+// narrowWidth is the terminal width below which the UI falls back to a
+// minimal layout: no progress bar, and lanes naming just their repo.
+const narrowWidth = 60
+
+// minBarWidth is the least the progress bar shrinks to on a terminal that's
+// not narrow yet.
+const minBarWidth = 10
+
+// barWidth is how wide the progress bar is on a terminal width wide: a
+// third of it, leaving the rest to the status.
+func barWidth(width int) int {
+	return max(minBarWidth, width/3)
+}
+
+// narrow reports whether the terminal is too narrow for the full layout.
+func (m model) narrow() bool {
+	return m.width > 0 && m.width < narrowWidth
+}
+
+// fit cuts line to width with an ellipsis, rather than letting the terminal
+// wrap it, which throws off how many lines the UI takes. A width of 0 isn't
+// known yet and leaves line alone.
+func fit(line string, width int) string {
+	if width <= 0 {
+		return line
+	}
+
+	return ansi.Truncate(line, width, "…")
+}
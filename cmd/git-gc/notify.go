@@ -0,0 +1,24 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// notifyDone rings the terminal bell and shows a desktop notification saying
+// how the run went, for --notify.
+func notifyDone(m model, took time.Duration) {
+	if isTerminal(os.Stdout) {
+		fmt.Print("\a")
+	}
+
+	title := tr("git-gc is done")
+	if n := len(m.failures); n > 0 {
+		title = tr("git-gc is done, %d repos failed", n)
+	}
+
+	if err := desktopNotify(title, runSummary(m, took)); err != nil {
+		fmt.Printf("Error sending the notification: %s\n", err)
+	}
+}
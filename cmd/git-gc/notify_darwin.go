@@ -0,0 +1,23 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// desktopNotify shows a notification in the Notification Center. The text
+// is passed as arguments, so that it needs no quoting in the script.
+func desktopNotify(title, body string) error {
+	out, err := exec.Command("osascript",
+		"-e", "on run argv",
+		"-e", "display notification (item 2 of argv) with title (item 1 of argv)",
+		"-e", "end run",
+		title, body,
+	).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("osascript: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
@@ -0,0 +1,16 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// desktopNotify shows a desktop notification with notify-send.
+func desktopNotify(title, body string) error {
+	if out, err := exec.Command("notify-send", "--app-name=git-gc", title, body).CombinedOutput(); err != nil {
+		return fmt.Errorf("notify-send: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin && !windows
+
+package main
+
+import "errors"
+
+// desktopNotify shows a desktop notification, which isn't supported here.
+func desktopNotify(string, string) error {
+	return errors.New("desktop notifications are not supported on this platform")
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/charmbracelet/bubbletea"
+)
+
+// repoOpened is sent once the command opening a repo with o exits.
+type repoOpened struct {
+	dir string
+	err error
+}
+
+func parseOpener(command string) (*template.Template, error) {
+	if strings.TrimSpace(command) == "" {
+		return nil, nil
+	}
+
+	tmpl, err := template.New("open").Option("missingkey=error").Parse(command)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse open: %w", err)
+	}
+
+	return tmpl, nil
+}
+
+// openerCommand returns the command opening path in the platform's file
+// manager, or the browser for a web page.
+func openerCommand(path string) *exec.Cmd {
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.Command("open", path)
+	case "windows":
+		// explorer exits with 1 even when it opened path
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", path)
+	}
+
+	return exec.Command("xdg-open", path)
+}
+
+// resultDirs returns the finished repos in the order the history shows them,
+// matching the filter.
+func (m model) resultDirs() []string {
+	lines := m.printed
+	if m.order != orderFinished {
+		lines = m.sortedHistory()
+	}
+
+	var dirs []string
+	for _, line := range lines {
+		if line.dir == "" || slices.Contains(dirs, line.dir) {
+			continue
+		}
+
+		if _, ok := fuzzyScore(m.filter, line.dir); m.filter == "" || ok {
+			dirs = append(dirs, line.dir)
+		}
+	}
+
+	return dirs
+}
+
+// selectResult selects the finished repo after the selected one, or before
+// it if step is -1. Going up from none selects the last one.
+func (m *model) selectResult(step int) {
+	dirs := m.resultDirs()
+	if len(dirs) == 0 {
+		return
+	}
+
+	i := slices.Index(dirs, m.result)
+	if i < 0 && step < 0 {
+		i = len(dirs)
+	}
+
+	m.result = dirs[(i+step+len(dirs))%len(dirs)]
+}
+
+// resultDir is the finished repo o and y act on: the selected one, or else
+// the last one to finish. It's empty before any finished.
+func (m model) resultDir() string {
+	if m.result != "" {
+		return m.result
+	}
+
+	dirs := m.resultDirs()
+	if len(dirs) == 0 {
+		return ""
+	}
+
+	return dirs[len(dirs)-1]
+}
+
+// openResult opens the resultDir with the open command from the config
+// file. Without one, the file manager opens it.
+func (m model) openResult() tea.Cmd {
+	dir := m.resultDir()
+	if dir == "" {
+		return nil
+	}
+
+	done := func(err error) tea.Msg { return repoOpened{dir: dir, err: err} }
+	if m.opener == nil {
+		cmd := openerCommand(dir)
+		return func() tea.Msg { return done(cmd.Run()) }
+	}
+
+	command, env, err := renderHook(m.opener, dir, hookData{})
+	if err != nil {
+		return func() tea.Msg { return done(err) }
+	}
+
+	// Editors such as vim take over the terminal until they exit
+	argv := shellCommand(command)
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	return tea.ExecProcess(cmd, done)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// repoOrder is the order repos are processed in.
+type repoOrder string
+
+const (
+	orderAlpha    repoOrder = "alpha"     // by path
+	orderSizeDesc repoOrder = "size-desc" // largest first, so the long ones don't start last
+	orderSizeAsc  repoOrder = "size-asc"  // smallest first
+	orderRandom   repoOrder = "random"    // shuffled, to stagger IO across machines sharing storage
+	orderMtime    repoOrder = "mtime"     // most recently changed first
+)
+
+var repoOrders = []repoOrder{orderAlpha, orderSizeDesc, orderSizeAsc, orderRandom, orderMtime}
+
+func parseRepoOrder(s string) (repoOrder, error) {
+	for _, o := range repoOrders {
+		if string(o) == s {
+			return o, nil
+		}
+	}
+
+	return "", fmt.Errorf("unknown order %q (available: %v)", s, repoOrders)
+}
+
+// sortRepos sorts dirs, which are sorted by path, in order. Repos whose size
+// or modification time can't be read sort as if they were empty or old.
+func sortRepos(dirs []string, order repoOrder) {
+	switch order {
+	case orderSizeDesc, orderSizeAsc:
+		sizes := make(map[string]int64, len(dirs))
+		for _, dir := range dirs {
+			stats, _ := inspectRepo(context.Background(), dir)
+			sizes[dir] = stats.packSize + stats.looseSize
+		}
+
+		slices.SortStableFunc(dirs, func(a, b string) int {
+			if order == orderSizeDesc {
+				return cmp.Compare(sizes[b], sizes[a])
+			}
+
+			return cmp.Compare(sizes[a], sizes[b])
+		})
+	case orderRandom:
+		rand.Shuffle(len(dirs), func(i, j int) { dirs[i], dirs[j] = dirs[j], dirs[i] })
+	case orderMtime:
+		mtimes := make(map[string]time.Time, len(dirs))
+		for _, dir := range dirs {
+			// Committing, checking out and fetching all replace files
+			// directly inside .git
+			if fi, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+				mtimes[dir] = fi.ModTime()
+			}
+		}
+
+		slices.SortStableFunc(dirs, func(a, b string) int {
+			return mtimes[b].Compare(mtimes[a])
+		})
+	}
+}
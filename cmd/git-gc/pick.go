@@ -0,0 +1,276 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// measurers is how many repos the picker measures at once.
+const measurers = 4
+
+// picker is the screen shown before the run to pick the repos to run on,
+// all of them to start with. Repo sizes show up as they're measured.
+type picker struct {
+	dirs   []string
+	sizes  []int64 // -1 until measured
+	chosen []bool
+	action string
+
+	// filter narrows the list down to the repos fuzzily matching it, the
+	// best matches first; visible are their indexes. filtering is set while
+	// the user types it.
+	filter    string
+	filtering bool
+	visible   []int
+
+	cursor int // index in visible
+	offset int // the first row shown
+	width  int
+	height int
+
+	confirmed bool
+	done      bool
+
+	check, cursorStyle, note lipgloss.Style
+}
+
+// sizeMeasured is sent once the size of a repo in the picker is known.
+type sizeMeasured struct {
+	index int
+	size  int64
+}
+
+// pickRepos lets the user pick which of dirs to run action on, in the
+// alternate screen if altScreen is set. It reports false if they quit
+// instead.
+func pickRepos(dirs []string, action string, st styles, altScreen bool) ([]string, bool, error) {
+	p := picker{
+		dirs:        dirs,
+		sizes:       make([]int64, len(dirs)),
+		chosen:      make([]bool, len(dirs)),
+		action:      action,
+		check:       st.checkmark.UnsetString(),
+		cursorStyle: st.currentDirName,
+		note:        st.note,
+	}
+	for i := range dirs {
+		p.sizes[i], p.chosen[i] = -1, true
+	}
+	p.applyFilter()
+
+	var opts []tea.ProgramOption
+	if altScreen {
+		opts = append(opts, tea.WithAltScreen())
+	}
+
+	final, err := tea.NewProgram(p, opts...).Run()
+	if err != nil {
+		return nil, false, err
+	}
+
+	p = final.(picker)
+	if !p.confirmed {
+		return nil, false, nil
+	}
+
+	var picked []string
+	for i, dir := range p.dirs {
+		if p.chosen[i] {
+			picked = append(picked, dir)
+		}
+	}
+
+	return picked, true, nil
+}
+
+func (p picker) Init() tea.Cmd {
+	cmds := make([]tea.Cmd, min(measurers, len(p.dirs)))
+	for i := range cmds {
+		cmds[i] = p.measure(i)
+	}
+
+	return tea.Batch(cmds...)
+}
+
+// measure measures the size of the i-th repo in the background, the same
+// way --order does.
+func (p picker) measure(i int) tea.Cmd {
+	dir := p.dirs[i]
+	return func() tea.Msg {
+		stats, _ := inspectRepo(context.Background(), dir)
+		return sizeMeasured{index: i, size: stats.packSize + stats.looseSize}
+	}
+}
+
+func (p picker) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		p.width, p.height = msg.Width, msg.Height
+	case sizeMeasured:
+		p.sizes[msg.index] = msg.size
+		if next := msg.index + measurers; next < len(p.dirs) {
+			return p, p.measure(next)
+		}
+	case tea.KeyMsg:
+		if p.filtering {
+			return p.typeFilter(msg)
+		}
+
+		switch msg.String() {
+		case "/":
+			p.filtering = true
+		case "up", "k":
+			p.cursor--
+		case "down", "j":
+			p.cursor++
+		case "pgup":
+			p.cursor -= p.rows()
+		case "pgdown":
+			p.cursor += p.rows()
+		case "home", "g":
+			p.cursor = 0
+		case "end", "G":
+			p.cursor = len(p.visible) - 1
+		case " ", "x":
+			if len(p.visible) > 0 {
+				i := p.visible[p.cursor]
+				p.chosen[i] = !p.chosen[i]
+			}
+		case "a":
+			p.chooseAll(true)
+		case "n":
+			p.chooseAll(false)
+		case "enter":
+			p.confirmed, p.done = true, true
+			return p, tea.Quit
+		case "esc":
+			if p.filter == "" {
+				p.done = true
+				return p, tea.Quit
+			}
+
+			p.filter = ""
+			p.applyFilter()
+		case "ctrl+c", "q":
+			p.done = true
+			return p, tea.Quit
+		}
+	}
+
+	return p.scrolled(), nil
+}
+
+// typeFilter edits the filter as the user types it after pressing /.
+func (p picker) typeFilter(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEnter:
+		p.filtering = false
+	case tea.KeyEsc:
+		p.filtering, p.filter = false, ""
+	case tea.KeyCtrlC:
+		p.done = true
+		return p, tea.Quit
+	default:
+		p.filter = editLine(p.filter, msg)
+	}
+
+	p.applyFilter()
+	return p.scrolled(), nil
+}
+
+// scrolled returns p with the cursor in the list, scrolled just enough to
+// show it.
+func (p picker) scrolled() picker {
+	p.cursor = max(0, min(p.cursor, len(p.visible)-1))
+	p.offset = max(min(p.offset, p.cursor), p.cursor-p.rows()+1)
+	return p
+}
+
+// applyFilter updates the visible repos after the filter changed, moving
+// the cursor to the best match.
+func (p *picker) applyFilter() {
+	p.visible, p.cursor, p.offset = p.visible[:0], 0, 0
+	scores := make(map[int]int)
+	for i, dir := range p.dirs {
+		if score, ok := fuzzyScore(p.filter, dir); ok {
+			p.visible = append(p.visible, i)
+			scores[i] = score
+		}
+	}
+
+	slices.SortStableFunc(p.visible, func(a, b int) int { return cmp.Compare(scores[b], scores[a]) })
+}
+
+// chooseAll picks all of the visible repos, or none of them.
+func (p *picker) chooseAll(chosen bool) {
+	for _, i := range p.visible {
+		p.chosen[i] = chosen
+	}
+}
+
+// rows is how many repos fit on the screen, below the title and above the
+// keys.
+func (p picker) rows() int {
+	if p.height == 0 {
+		return len(p.dirs)
+	}
+
+	return max(1, p.height-4)
+}
+
+func (p picker) View() string {
+	if p.done {
+		return ""
+	}
+
+	var count int
+	var size int64
+	for i, chosen := range p.chosen {
+		if chosen {
+			count++
+			size += max(0, p.sizes[i])
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(tr("Pick the repos to run %s on: %d of %d, %s", p.action, count, len(p.dirs), formatSize(size)) + "\n")
+	switch {
+	case p.filtering:
+		b.WriteString(p.cursorStyle.Render(tr("Filter: ")) + p.filter + "█" + p.note.Render(tr(" (enter to keep, esc to clear)")) + "\n")
+	case p.filter != "":
+		b.WriteString(p.note.Render(tr("Matching %q: %d repos, / to change, esc to clear", p.filter, len(p.visible))) + "\n")
+	default:
+		b.WriteString("\n")
+	}
+
+	row := lipgloss.NewStyle().MaxWidth(max(0, p.width))
+	for n := p.offset; n < min(len(p.visible), p.offset+p.rows()); n++ {
+		i := p.visible[n]
+		box, size := "[ ]", "…"
+		if p.chosen[i] {
+			box = p.check.Render("[x]")
+		}
+
+		if p.sizes[i] >= 0 {
+			size = formatSize(p.sizes[i])
+		}
+
+		line := fmt.Sprintf("%s %10s  %s", box, size, p.dirs[i])
+		if n == p.cursor {
+			line = p.cursorStyle.Render("›") + " " + line
+		} else {
+			line = "  " + line
+		}
+
+		b.WriteString(row.Render(line) + "\n")
+	}
+
+	b.WriteString(p.note.Render("space: toggle • a: all • n: none • /: filter • enter: start • q: quit"))
+	return b.String()
+}
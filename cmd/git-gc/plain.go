@@ -0,0 +1,59 @@
+package main
+
+import (
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbletea"
+)
+
+// isTerminal reports whether f is a terminal, rather than a file or pipe the
+// UI's escape sequences would garble.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// println adds a line to the history above the UI, or logs it without the
+// UI unless quiet.
+func (m *model) println(line string) tea.Cmd {
+	return m.printRepo("", line)
+}
+
+// printRepo is println for a line about dir, which the history can be
+// filtered by.
+func (m *model) printRepo(dir, line string) tea.Cmd {
+	if m.quiet {
+		return nil
+	}
+
+	if m.plain != nil {
+		m.plain.Print(line)
+		return nil
+	}
+
+	if m.compact {
+		return nil
+	}
+
+	for _, text := range strings.Split(line, "\n") {
+		m.printed = append(m.printed, historyLine{dir: dir, text: text})
+	}
+
+	m.syncHistory()
+	return nil
+}
+
+// logRun logs how a run went: one line, plus one per failed repo. It
+// returns the line.
+func logRun(logger *log.Logger, m model, took time.Duration) string {
+	summary := runSummary(m, took)
+	logger.Print(tr("Ran %s", summary))
+	for _, f := range m.failures {
+		logger.Print(tr("Failed %s: %s: %v", f.dir, f.task, f.err))
+	}
+
+	return summary
+}
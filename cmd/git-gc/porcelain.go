@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// porcelain writes what happens in the run as JSON lines for --porcelain,
+// one object per event, for wrappers showing their own progress.
+type porcelain struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// porcelainEvent is a line of --porcelain output. Event is one of
+// "scan-started", "repo-found", "repo-started", "repo-finished" and
+// "run-finished".
+type porcelainEvent struct {
+	Event string    `json:"event"`
+	Time  time.Time `json:"time"`
+	Root  string    `json:"root,omitempty"` // scan-started
+	Path  string    `json:"path,omitempty"` // repo-found, repo-started and repo-finished
+
+	Repo *repoReport `json:"repo,omitempty"` // repo-finished
+	Run  *runTotals  `json:"run,omitempty"`  // run-finished
+}
+
+func newPorcelain(w io.Writer) *porcelain {
+	return &porcelain{enc: json.NewEncoder(w)}
+}
+
+// emit writes ev, which happened now. A nil porcelain is a no-op.
+func (p *porcelain) emit(ev porcelainEvent) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ev.Time = time.Now()
+	_ = p.enc.Encode(ev)
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// powerSaving returns why the machine should be spared, "on battery" or "in
+// low power mode", or "" if it's plugged in or that isn't known.
+func powerSaving() string {
+	// Now drawing from 'Battery Power'
+	if out, err := exec.Command("pmset", "-g", "batt").Output(); err == nil &&
+		strings.Contains(string(out), "'Battery Power'") {
+		return "on battery"
+	}
+
+	// Low Power Mode is on when any power source has " lowpowermode 1"
+	if out, err := exec.Command("pmset", "-g").Output(); err == nil {
+		for _, line := range strings.Split(string(out), "\n") {
+			if f := strings.Fields(line); len(f) == 2 && f[0] == "lowpowermode" && f[1] == "1" {
+				return "in low power mode"
+			}
+		}
+	}
+
+	return ""
+}
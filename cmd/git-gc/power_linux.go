@@ -0,0 +1,41 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// powerSaving returns why the machine should be spared, "on battery" or "in
+// low power mode", or "" if it's plugged in or that isn't known.
+func powerSaving() string {
+	supplies, _ := filepath.Glob("/sys/class/power_supply/*")
+
+	var onAC, discharging bool
+	for _, dir := range supplies {
+		switch readSysfs(filepath.Join(dir, "type")) {
+		case "Mains", "USB":
+			onAC = onAC || readSysfs(filepath.Join(dir, "online")) == "1"
+		case "Battery":
+			discharging = discharging || readSysfs(filepath.Join(dir, "status")) == "Discharging"
+		}
+	}
+
+	switch {
+	case discharging && !onAC:
+		return "on battery"
+	case readSysfs("/sys/firmware/acpi/platform_profile") == "low-power":
+		return "in low power mode"
+	default:
+		return ""
+	}
+}
+
+func readSysfs(path string) string {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(b))
+}
@@ -0,0 +1,9 @@
+//go:build !linux && !darwin && !windows
+
+package main
+
+// powerSaving returns why the machine should be spared, which isn't known
+// here.
+func powerSaving() string {
+	return ""
+}
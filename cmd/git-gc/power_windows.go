@@ -0,0 +1,33 @@
+package main
+
+import "unsafe"
+
+var procGetSystemPowerStatus = kernel32.NewProc("GetSystemPowerStatus")
+
+// systemPowerStatus is SYSTEM_POWER_STATUS.
+type systemPowerStatus struct {
+	acLineStatus        byte
+	batteryFlag         byte
+	batteryLifePercent  byte
+	systemStatusFlag    byte
+	batteryLifeTime     uint32
+	batteryFullLifeTime uint32
+}
+
+// powerSaving returns why the machine should be spared, "on battery" or "in
+// low power mode", or "" if it's plugged in or that isn't known.
+func powerSaving() string {
+	var s systemPowerStatus
+	if ok, _, _ := procGetSystemPowerStatus.Call(uintptr(unsafe.Pointer(&s))); ok == 0 {
+		return ""
+	}
+
+	switch {
+	case s.acLineStatus == 0:
+		return "on battery"
+	case s.systemStatusFlag == 1:
+		return "in low power mode" // battery saver
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// shallowPolicy is what to do with shallow clones.
+type shallowPolicy string
+
+const (
+	shallowGC        shallowPolicy = "gc"        // treat them like full clones
+	shallowSkip      shallowPolicy = "skip"      // leave them alone
+	shallowUnshallow shallowPolicy = "unshallow" // fetch --unshallow before the tasks
+)
+
+var shallowPolicies = []shallowPolicy{shallowGC, shallowSkip, shallowUnshallow}
+
+func parseShallowPolicy(s string) (shallowPolicy, error) {
+	for _, p := range shallowPolicies {
+		if string(p) == s {
+			return p, nil
+		}
+	}
+
+	return "", fmt.Errorf("unknown shallow policy %q (available: %v)", s, shallowPolicies)
+}
+
+// annexPolicy is what to do with git-annex repos, whose unreferenced
+// objects must not be pruned carelessly.
+type annexPolicy string
+
+const (
+	annexSkip annexPolicy = "skip" // leave them alone
+	annexSafe annexPolicy = "safe" // gc without pruning, and report unused annex content
+	annexGC   annexPolicy = "gc"   // treat them like any other repo
+)
+
+var annexPolicies = []annexPolicy{annexSkip, annexSafe, annexGC}
+
+func parseAnnexPolicy(s string) (annexPolicy, error) {
+	for _, p := range annexPolicies {
+		if string(p) == s {
+			return p, nil
+		}
+	}
+
+	return "", fmt.Errorf("unknown annex policy %q (available: %v)", s, annexPolicies)
+}
+
+// preflight inspects a repo right before its first task runs. It can decide
+// to skip the repo entirely, or prepare it for the pipeline.
+type preflight struct {
+	shallow    shallowPolicy
+	annex      annexPolicy
+	cleanStale bool  // remove gc.pid/gc.log files left behind by a crashed gc
+	minFree    int64 // bytes to keep free on top of the repo's packs, 0 to not check
+
+	// changedOnly skips repos whose fingerprint is the same as after their
+	// last successful run of the same tasks, which is kept in state. A repo
+	// that was only fsck'd still needs its first gc.
+	changedOnly bool
+	tasks       string // the names of the tasks of the pipeline, sorted
+	state       *stateStore
+}
+
+type preflightResult struct {
+	skip string // reason to skip the repo, if any
+	note string // what was done to prepare the repo, if anything
+}
+
+func (p preflight) run(ctx context.Context, dir string) (preflightResult, error) {
+	if p.annex == annexSkip && isAnnex(ctx, dir) {
+		return preflightResult{skip: "git-annex repo, pass --annex=safe to include it"}, nil
+	}
+
+	gitDir, err := absoluteGitDir(ctx, dir)
+	if err != nil {
+		return preflightResult{}, err
+	}
+
+	// Running another gc alongside the one already going, e.g. an IDE's
+	// background maintenance, would only make them fight over the packs
+	lock, locked, err := readGCLock(gitDir)
+	if err != nil {
+		return preflightResult{}, err
+	}
+
+	if locked && lock.held() {
+		return preflightResult{skip: fmt.Sprintf("locked, git gc already running as pid %d on %s", lock.pid, lock.host)}, nil
+	}
+
+	if p.changedOnly {
+		if fp, err := repoFingerprint(ctx, dir); err == nil && fp == p.state.fingerprint(dir, p.tasks) {
+			return preflightResult{skip: "unchanged since the last run"}, nil
+		}
+	}
+
+	if skip, err := p.checkFreeSpace(ctx, dir); err != nil || skip != "" {
+		return preflightResult{skip: skip}, err
+	}
+
+	staleNote, err := p.checkStaleGCFiles(gitDir)
+	if err != nil {
+		return preflightResult{}, err
+	}
+
+	res, err := p.checkShallow(ctx, dir)
+	res.note = joinNotes(staleNote, res.note)
+	return res, err
+}
+
+// checkStaleGCFiles removes, or warns about, leftovers of a crashed gc.
+func (p preflight) checkStaleGCFiles(gitDir string) (string, error) {
+	stale, err := staleGCFiles(gitDir)
+	if err != nil || len(stale) == 0 {
+		return "", err
+	}
+
+	if !p.cleanStale {
+		return fmt.Sprintf("stale %s found, pass --clean-stale-locks to remove", strings.Join(stale, " and ")), nil
+	}
+
+	for _, name := range stale {
+		if err := os.Remove(filepath.Join(gitDir, name)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return "", fmt.Errorf("could not remove stale %s: %w", name, err)
+		}
+	}
+
+	return "removed stale " + strings.Join(stale, " and "), nil
+}
+
+// recordFingerprint remembers what dir looks like after a successful run,
+// for changedOnly. A repo whose fingerprint can't be recorded is simply
+// processed again next time.
+func (p preflight) recordFingerprint(ctx context.Context, dir string) {
+	if fp, err := repoFingerprint(ctx, dir); err == nil {
+		_ = p.state.recordFingerprint(dir, p.tasks, fp)
+	}
+}
+
+// checkFreeSpace returns why to skip a repo whose filesystem doesn't have
+// room for a copy of its packs, which gc writes before deleting the old ones,
+// plus minFree. Running out of space halfway only makes things worse.
+func (p preflight) checkFreeSpace(ctx context.Context, dir string) (string, error) {
+	if p.minFree <= 0 {
+		return "", nil
+	}
+
+	free, ok := diskFree(dir)
+	if !ok {
+		return "", nil
+	}
+
+	stats, err := inspectRepo(ctx, dir)
+	if err != nil {
+		return "", err
+	}
+
+	if need := stats.packSize + p.minFree; free < need {
+		return fmt.Sprintf("low on disk space, %s free but gc needs %s", formatSize(free), formatSize(need)), nil
+	}
+
+	return "", nil
+}
+
+func (p preflight) checkShallow(ctx context.Context, dir string) (preflightResult, error) {
+	if p.shallow != shallowGC && isShallow(ctx, dir) {
+		if p.shallow == shallowSkip {
+			return preflightResult{skip: "shallow clone"}, nil
+		}
+
+		cmd, exited := groupCommand(ctx, dir, gitPath, "-C", dir, "fetch", "--unshallow", "--quiet")
+		stderr := &bytes.Buffer{}
+		cmd.Stdout = io.Discard
+		cmd.Stderr = stderr
+		err := cmd.Run()
+		exited()
+		if err != nil {
+			if ctx.Err() != nil {
+				return preflightResult{}, fmt.Errorf("could not unshallow: %w", contextError(ctx))
+			}
+
+			return preflightResult{}, fmt.Errorf("could not unshallow: %w", gitError(err, stderr.String()))
+		}
+
+		return preflightResult{note: "unshallowed"}, nil
+	}
+
+	return preflightResult{}, nil
+}
+
+func isAnnex(ctx context.Context, dir string) bool {
+	out, err := gitOutput(ctx, dir, "config", "--get", "annex.uuid")
+	return err == nil && strings.TrimSpace(string(out)) != ""
+}
+
+// annexUnused returns how many annexed files are no longer used by any
+// branch, as reported by `git annex unused`.
+func annexUnused(ctx context.Context, dir string) (int, error) {
+	out, err := gitOutput(ctx, dir, "annex", "unused", "--quiet")
+	if err != nil {
+		return 0, err
+	}
+
+	// Unused content is listed as "    NUMBER KEY" lines
+	var n int
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && strings.Trim(fields[0], "0123456789") == "" {
+			n++
+		}
+	}
+
+	return n, nil
+}
+
+func isShallow(ctx context.Context, dir string) bool {
+	out, err := gitOutput(ctx, dir, "rev-parse", "--is-shallow-repository")
+	return err == nil && strings.TrimSpace(string(out)) == "true"
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+const (
+	prioDarwinProcess  = 4      // PRIO_DARWIN_PROCESS
+	prioDarwinBG       = 0x1000 // PRIO_DARWIN_BG, what `taskpolicy -b` sets
+	backgroundNiceness = 10
+)
+
+// startLowPriority starts cmd, lowers the CPU priority of its process group
+// and puts it in the background policy, which also throttles its IO.
+func startLowPriority(cmd *exec.Cmd) error {
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	pid := cmd.Process.Pid
+	_ = syscall.Setpriority(syscall.PRIO_PGRP, pid, backgroundNiceness)
+	_ = syscall.Setpriority(prioDarwinProcess, pid, prioDarwinBG)
+	return nil
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// ioprio_set(2) arguments, from linux/ioprio.h
+const (
+	ioprioWhoPgrp      = 2
+	ioprioClassBE      = 2
+	ioprioClassShift   = 13
+	lowestBestEffort   = ioprioClassBE<<ioprioClassShift | 7
+	backgroundNiceness = 10
+)
+
+// startLowPriority starts cmd and lowers the CPU (nice) and IO (ionice)
+// priority of its process group, which the processes it spawns inherit.
+func startLowPriority(cmd *exec.Cmd) error {
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	pgid := cmd.Process.Pid
+	_ = syscall.Setpriority(syscall.PRIO_PGRP, pgid, backgroundNiceness)
+	_, _, _ = syscall.Syscall(syscall.SYS_IOPRIO_SET, ioprioWhoPgrp, uintptr(pgid), lowestBestEffort)
+	return nil
+}
@@ -0,0 +1,21 @@
+//go:build unix && !linux && !darwin
+
+package main
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+const backgroundNiceness = 10
+
+// startLowPriority starts cmd and lowers the CPU priority of its process
+// group. IO priority can't be changed here.
+func startLowPriority(cmd *exec.Cmd) error {
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	_ = syscall.Setpriority(syscall.PRIO_PGRP, cmd.Process.Pid, backgroundNiceness)
+	return nil
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+const belowNormalPriorityClass = 0x00004000 // BELOW_NORMAL_PRIORITY_CLASS
+
+// startLowPriority starts cmd in the below normal priority class, which the
+// processes it spawns inherit.
+func startLowPriority(cmd *exec.Cmd) error {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+
+	cmd.SysProcAttr.CreationFlags |= belowNormalPriorityClass
+	return cmd.Start()
+}
@@ -0,0 +1,42 @@
+//go:build unix
+
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// processAlive reports whether a process with the given pid is running.
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// setProcessGroup makes cmd start in a process group of its own, which the
+// processes git spawns in turn (e.g. pack-objects) join.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// terminate asks the process group led by p to exit.
+func terminate(p *os.Process) error {
+	return syscall.Kill(-p.Pid, syscall.SIGTERM)
+}
+
+// suspendGroup stops the process group led by p until resumeGroup.
+func suspendGroup(p *os.Process) error {
+	return syscall.Kill(-p.Pid, syscall.SIGSTOP)
+}
+
+// resumeGroup continues the process group led by p after suspendGroup.
+func resumeGroup(p *os.Process) error {
+	return syscall.Kill(-p.Pid, syscall.SIGCONT)
+}
+
+// killGroup kills the process group led by p.
+func killGroup(p *os.Process) error {
+	return syscall.Kill(-p.Pid, syscall.SIGKILL)
+}
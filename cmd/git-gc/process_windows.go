@@ -0,0 +1,54 @@
+//go:build windows
+
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+const stillActive = 259 // STILL_ACTIVE exit code of a running process
+
+// processAlive reports whether a process with the given pid is running.
+func processAlive(pid int) bool {
+	h, err := syscall.OpenProcess(syscall.PROCESS_QUERY_INFORMATION, false, uint32(pid))
+	if err != nil {
+		return false
+	}
+	defer syscall.CloseHandle(h)
+
+	var code uint32
+	if err := syscall.GetExitCodeProcess(h, &code); err != nil {
+		return false
+	}
+
+	return code == stillActive
+}
+
+// setProcessGroup makes cmd start in a process group of its own.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+}
+
+// terminate asks p to exit. Windows has no signal to ask politely with, so
+// its process tree is killed right away.
+func terminate(p *os.Process) error {
+	return killGroup(p)
+}
+
+// suspendGroup would stop p until resumeGroup, which isn't supported here.
+func suspendGroup(*os.Process) error {
+	return errors.ErrUnsupported
+}
+
+func resumeGroup(*os.Process) error {
+	return errors.ErrUnsupported
+}
+
+// killGroup kills p along with the processes it started.
+func killGroup(p *os.Process) error {
+	return exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(p.Pid)).Run()
+}
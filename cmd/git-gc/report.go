@@ -0,0 +1,329 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// reportFormat is the format --report writes the report in.
+type reportFormat string
+
+const (
+	reportJSON     reportFormat = "json"
+	reportCSV      reportFormat = "csv" // a row per repo, for spreadsheets
+	reportTSV      reportFormat = "tsv"
+	reportMarkdown reportFormat = "markdown" // a table and totals, for PR descriptions and wikis
+	reportHTML     reportFormat = "html"     // a page with charts, to open in a browser
+	reportJUnit    reportFormat = "junit"    // a test case per repo, for CI systems
+)
+
+var reportFormats = []reportFormat{reportJSON, reportCSV, reportTSV, reportMarkdown, reportHTML, reportJUnit}
+
+// reportExtensions are the formats of report files by their extension, for
+// when the format isn't given.
+var reportExtensions = map[string]reportFormat{
+	".csv":      reportCSV,
+	".tsv":      reportTSV,
+	".md":       reportMarkdown,
+	".markdown": reportMarkdown,
+	".html":     reportHTML,
+	".htm":      reportHTML,
+	".xml":      reportJUnit,
+}
+
+// parseReportFormat parses the format of the report file at path. Without
+// one, it goes by the file's extension, and is JSON for others.
+func parseReportFormat(s, path string) (reportFormat, error) {
+	if s == "" {
+		return cmp.Or(reportExtensions[strings.ToLower(filepath.Ext(path))], reportJSON), nil
+	}
+
+	for _, f := range reportFormats {
+		if string(f) == s {
+			return f, nil
+		}
+	}
+
+	return "", fmt.Errorf("unknown report format %q (available: %v)", s, reportFormats)
+}
+
+// runReport is what --report writes once the run is over, for tools
+// collecting the results of many machines.
+type runReport struct {
+	Root     string    `json:"root"`
+	Host     string    `json:"host"`
+	Tasks    []string  `json:"tasks"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	runTotals
+
+	Repos []repoReport `json:"repos"`
+}
+
+// runTotals sums up how a run went.
+type runTotals struct {
+	Duration  float64 `json:"duration_seconds"`
+	Stopped   bool    `json:"stopped"` // the user quit before every repo was done
+	ExitCode  int     `json:"exit_code"`
+	Succeeded int     `json:"succeeded"`
+	Failed    int     `json:"failed"`
+	Skipped   int     `json:"skipped"`
+	NotRun    int     `json:"not_run"`
+	Freed     int64   `json:"freed_bytes"`
+}
+
+// repoReport is how a repo of the run ended. Status is "succeeded",
+// "failed", "interrupted" (by the user quitting), "skipped" or "not_run",
+// e.g. because the budget ran out.
+type repoReport struct {
+	Path     string   `json:"path"`
+	Status   string   `json:"status"`
+	Tasks    []string `json:"tasks"` // the tasks that ran, up to the failed one
+	Duration float64  `json:"duration_seconds,omitempty"`
+	Before   int64    `json:"bytes_before,omitempty"`
+	After    int64    `json:"bytes_after,omitempty"`
+
+	FailedTask string   `json:"failed_task,omitempty"`
+	Error      string   `json:"error,omitempty"`
+	Stderr     []string `json:"stderr,omitempty"` // the end of what the failed task wrote
+	SkipReason string   `json:"skip_reason,omitempty"`
+}
+
+// newReport describes the run of m in root, which started at start.
+func newReport(m model, root string, start time.Time) runReport {
+	host, _ := os.Hostname()
+	r := runReport{
+		Root:     root,
+		Host:     host,
+		Tasks:    m.taskNames(),
+		Started:  start,
+		Finished: time.Now(),
+		Repos:    make([]repoReport, 0, len(m.directories)),
+	}
+
+	for _, dir := range m.directories {
+		r.Repos = append(r.Repos, m.repoReport(dir))
+	}
+
+	r.runTotals = m.totals(r.Repos, r.Finished.Sub(start))
+	return r
+}
+
+// taskNames returns the names of the tasks of the pipeline.
+func (m model) taskNames() []string {
+	names := make([]string, len(m.pipeline))
+	for i, t := range m.pipeline {
+		names[i] = t.name
+	}
+
+	return names
+}
+
+// repoReport describes how dir ended.
+func (m model) repoReport(dir string) repoReport {
+	tasks := m.taskNames()
+	repo := repoReport{Path: dir, Status: "not_run", Tasks: []string{}}
+	if result, ok := m.results[dir]; ok {
+		repo.Status = "succeeded"
+		repo.Tasks = tasks
+		repo.Duration = m.took[dir].Seconds()
+		repo.Before, repo.After = result.before, result.after
+	}
+
+	// A repo that failed the first time and succeeded when run again has no
+	// failures left
+	if i := slices.IndexFunc(m.failures, func(f repoFailure) bool { return f.dir == dir }); i >= 0 {
+		f := m.failures[i]
+		repo.Status = "failed"
+		if errors.Is(f.err, errInterrupted) {
+			repo.Status = "interrupted"
+		}
+
+		if step := slices.Index(tasks, f.task); step >= 0 {
+			repo.Tasks = tasks[:step+1]
+		}
+
+		repo.FailedTask, repo.Error, repo.Stderr = f.task, f.err.Error(), f.output
+	}
+
+	if i := slices.IndexFunc(m.skipped, func(s repoSkip) bool { return s.dir == dir }); i >= 0 {
+		repo.Status, repo.Tasks, repo.SkipReason = "skipped", []string{}, m.skipped[i].reason
+	}
+
+	return repo
+}
+
+// totals sums up the run of m from how its repos ended, and how long it
+// took.
+func (m model) totals(repos []repoReport, took time.Duration) runTotals {
+	t := runTotals{Duration: took.Seconds(), Stopped: m.quitting, ExitCode: m.exitCode(), Freed: m.reclaim}
+	for _, repo := range repos {
+		switch repo.Status {
+		case "succeeded":
+			t.Succeeded++
+		case "failed", "interrupted":
+			t.Failed++
+		case "skipped":
+			t.Skipped++
+		default:
+			t.NotRun++
+		}
+	}
+
+	return t
+}
+
+// writeReport writes the report of the run of m in root to path in format.
+func writeReport(path string, format reportFormat, m model, root string, start time.Time) error {
+	r := newReport(m, root, start)
+
+	var out []byte
+	var err error
+	switch format {
+	case reportCSV:
+		out, err = r.table(',')
+	case reportTSV:
+		out, err = r.table('\t')
+	case reportMarkdown:
+		out = r.markdown()
+	case reportHTML:
+		out, err = r.html()
+	case reportJUnit:
+		out, err = r.junit()
+	default:
+		out, err = json.MarshalIndent(r, "", "  ")
+		out = append(out, '\n')
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, out, 0o644)
+}
+
+// table renders the repos of the report as a row each, with a header, with
+// fields separated by comma. Sizes that weren't measured are left empty.
+func (r runReport) table(comma rune) ([]byte, error) {
+	var b bytes.Buffer
+	w := csv.NewWriter(&b)
+	w.Comma = comma
+	_ = w.Write([]string{"path", "status", "duration_seconds", "bytes_before", "bytes_after", "freed_bytes", "failed_task", "error"})
+
+	size := func(n int64) string {
+		if n == 0 {
+			return ""
+		}
+
+		return strconv.FormatInt(n, 10)
+	}
+
+	for _, repo := range r.Repos {
+		var freed string
+		if repo.Before > 0 && repo.After > 0 {
+			freed = strconv.FormatInt(repo.Before-repo.After, 10)
+		}
+
+		_ = w.Write([]string{
+			repo.Path,
+			repo.Status,
+			strconv.FormatFloat(repo.Duration, 'f', 3, 64),
+			size(repo.Before),
+			size(repo.After),
+			freed,
+			repo.FailedTask,
+			repo.Error,
+		})
+	}
+
+	w.Flush()
+	return b.Bytes(), w.Error()
+}
+
+// markdown renders the report as a heading, the totals, and a table with a
+// row per repo.
+func (r runReport) markdown() []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## git-gc on %s\n\n", r.Host)
+	fmt.Fprintf(&b, "Ran %s on the repositories under %s, starting %s, in %s: %d succeeded, %d failed, %d skipped, %d not run.",
+		strings.Join(r.Tasks, ", "), markdownCode(r.Root), r.Started.Format("2006-01-02 15:04"), formatTook(seconds(r.Duration)),
+		r.Succeeded, r.Failed, r.Skipped, r.NotRun)
+	switch {
+	case r.Freed > 0:
+		fmt.Fprintf(&b, " Freed %s in total.", formatSize(r.Freed))
+	case r.Freed < 0:
+		fmt.Fprintf(&b, " Grew by %s in total.", formatSize(-r.Freed))
+	}
+
+	b.WriteString("\n\n| Repository | Status | Duration | Before | After | Notes |\n")
+	b.WriteString("| --- | --- | ---: | ---: | ---: | --- |\n")
+	for _, repo := range r.Repos {
+		var took, before, after string
+		if repo.Duration > 0 {
+			took = formatTook(seconds(repo.Duration))
+		}
+
+		if repo.Before > 0 {
+			before = formatSize(repo.Before)
+		}
+
+		if repo.After > 0 {
+			after = formatSize(repo.After)
+		}
+
+		notes := repo.SkipReason
+		if repo.Error != "" {
+			notes = repo.FailedTask + ": " + repo.Error
+		}
+
+		fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s |\n",
+			markdownCell(markdownCode(repo.Path)), repo.Status, took, before, after, markdownCell(notes))
+	}
+
+	return []byte(b.String())
+}
+
+// markdownCode renders s as inline code. Backticks in s, which paths may
+// have, would end a code span of single backticks, so it's delimited by
+// more of them than s has in a row.
+func markdownCode(s string) string {
+	longest, run := 0, 0
+	for _, c := range s {
+		if c != '`' {
+			run = 0
+			continue
+		}
+
+		run++
+		longest = max(longest, run)
+	}
+
+	// A space keeps a backtick at either end from joining the delimiters,
+	// and is stripped when it's rendered
+	if strings.HasPrefix(s, "`") || strings.HasSuffix(s, "`") {
+		s = " " + s + " "
+	}
+
+	fence := strings.Repeat("`", longest+1)
+	return fence + s + fence
+}
+
+// markdownCell escapes s for a cell of a markdown table.
+func markdownCell(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
+
+// seconds converts a duration in seconds, as reported, back.
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"html/template"
+	"slices"
+	"strings"
+	"time"
+)
+
+// chartRepos is how many of the repos that freed the most space the HTML
+// report charts.
+const chartRepos = 20
+
+// durationBuckets are the upper bounds of the bars of the HTML report's
+// chart of how long repos took.
+var durationBuckets = []time.Duration{
+	time.Second, 5 * time.Second, 15 * time.Second, time.Minute, 5 * time.Minute, 15 * time.Minute,
+}
+
+// chartBar is a bar of a chart of the HTML report, with its width in
+// percent of the longest one.
+type chartBar struct {
+	Label string
+	Value string
+	Width float64
+}
+
+var htmlReport = template.Must(template.New("report").Funcs(template.FuncMap{
+	"join": strings.Join,
+	"took": func(s float64) string { return formatTook(seconds(s)) },
+	"size": formatSize,
+	"neg":  func(n int64) int64 { return -n },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>git-gc on {{.Host}}</title>
+<style>
+  body { font: 14px/1.5 system-ui, sans-serif; margin: 2em auto; max-width: 60em; padding: 0 1em; color: #222; }
+  h1 { font-size: 1.5em; }
+  h2 { font-size: 1.2em; margin-top: 2em; }
+  code { font-size: 0.95em; }
+  .chart { display: grid; grid-template-columns: max-content 1fr max-content; gap: 0.25em 0.75em; align-items: center; }
+  .bar { background: #5f87d7; height: 1em; border-radius: 2px; min-width: 1px; }
+  .label { overflow: hidden; text-overflow: ellipsis; white-space: nowrap; max-width: 30em; }
+  table { border-collapse: collapse; width: 100%; }
+  th, td { text-align: left; padding: 0.25em 0.5em; border-bottom: 1px solid #ddd; vertical-align: top; }
+  td.number { text-align: right; white-space: nowrap; }
+  .succeeded { color: #2e7d32; }
+  .failed, .interrupted { color: #c62828; }
+  .skipped, .not_run { color: #777; }
+  @media (prefers-color-scheme: dark) {
+    body { background: #1e1e1e; color: #ddd; }
+    th, td { border-color: #444; }
+  }
+</style>
+</head>
+<body>
+<h1>git-gc on {{.Host}}</h1>
+<p>Ran {{join .Tasks ", "}} on the repositories under <code>{{.Root}}</code>, starting {{.Started.Format "2006-01-02 15:04"}}, in {{took .Duration}}:
+{{.Succeeded}} succeeded, {{.Failed}} failed, {{.Skipped}} skipped, {{.NotRun}} not run.
+{{- if gt .Freed 0}} Freed {{size .Freed}} in total.{{else if lt .Freed 0}} Grew by {{size (neg .Freed)}} in total.{{end}}</p>
+{{- if .FreedBars}}
+<h2>Space freed</h2>
+<div class="chart">
+{{- range .FreedBars}}
+  <code class="label" title="{{.Label}}">{{.Label}}</code><div class="bar" style="width: {{.Width}}%"></div><span>{{.Value}}</span>
+{{- end}}
+</div>
+{{- end}}
+{{- if .DurationBars}}
+<h2>Durations</h2>
+<div class="chart">
+{{- range .DurationBars}}
+  <span class="label">{{.Label}}</span><div class="bar" style="width: {{.Width}}%"></div><span>{{.Value}}</span>
+{{- end}}
+</div>
+{{- end}}
+<h2>Repositories</h2>
+<table>
+<tr><th>Repository</th><th>Status</th><th>Duration</th><th>Before</th><th>After</th><th>Notes</th></tr>
+{{- range .Repos}}
+<tr>
+  <td><code>{{.Path}}</code></td>
+  <td class="{{.Status}}">{{.Status}}</td>
+  <td class="number">{{if .Duration}}{{took .Duration}}{{end}}</td>
+  <td class="number">{{if .Before}}{{size .Before}}{{end}}</td>
+  <td class="number">{{if .After}}{{size .After}}{{end}}</td>
+  <td>{{if .Error}}{{.FailedTask}}: {{.Error}}{{else}}{{.SkipReason}}{{end}}</td>
+</tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
+
+// html renders the report as a page that needs nothing else, with charts of
+// the space freed per repo and how long they took.
+func (r runReport) html() ([]byte, error) {
+	var b bytes.Buffer
+	err := htmlReport.Execute(&b, struct {
+		runReport
+		FreedBars, DurationBars []chartBar
+	}{r, r.freedBars(), r.durationBars()})
+
+	return b.Bytes(), err
+}
+
+// freedBars charts the repos that freed the most space.
+func (r runReport) freedBars() []chartBar {
+	repos := slices.DeleteFunc(slices.Clone(r.Repos), func(repo repoReport) bool {
+		return repo.After == 0 || repo.Before <= repo.After
+	})
+
+	slices.SortFunc(repos, func(a, b repoReport) int {
+		return cmp.Compare(b.Before-b.After, a.Before-a.After)
+	})
+
+	repos = repos[:min(chartRepos, len(repos))]
+	bars := make([]chartBar, len(repos))
+	for i, repo := range repos {
+		freed := repo.Before - repo.After
+		bars[i] = chartBar{
+			Label: repo.Path,
+			Value: formatSize(freed),
+			Width: 100 * float64(freed) / float64(repos[0].Before-repos[0].After),
+		}
+	}
+
+	return bars
+}
+
+// durationBars charts how many repos took how long, in durationBuckets.
+func (r runReport) durationBars() []chartBar {
+	counts := make([]int, len(durationBuckets)+1)
+	var most int
+	for _, repo := range r.Repos {
+		if repo.Duration == 0 {
+			continue
+		}
+
+		i, _ := slices.BinarySearch(durationBuckets, seconds(repo.Duration))
+		counts[i]++
+		most = max(most, counts[i])
+	}
+
+	if most == 0 {
+		return nil
+	}
+
+	bars := make([]chartBar, len(counts))
+	for i, n := range counts {
+		label := "over " + roundDuration(durationBuckets[len(durationBuckets)-1])
+		if i < len(durationBuckets) {
+			label = "up to " + roundDuration(durationBuckets[i])
+		}
+
+		bars[i] = chartBar{Label: label, Value: fmt.Sprint(n), Width: 100 * float64(n) / float64(most)}
+	}
+
+	return bars
+}
+
+// roundDuration formats a whole number of seconds, minutes or hours without
+// the zeros, e.g. 5m rather than 5m0s.
+func roundDuration(d time.Duration) string {
+	return strings.TrimSuffix(strings.TrimSuffix(d.String(), "0s"), "0m")
+}
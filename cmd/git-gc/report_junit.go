@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+// junitSuites is a JUnit XML report, which CI systems show as test results:
+// a test suite for the run, and a test case for each repo.
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Name     string       `xml:"name,attr"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Skipped  int          `xml:"skipped,attr"`
+	Time     float64      `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name      string      `xml:"name,attr"`
+	Hostname  string      `xml:"hostname,attr"`
+	Timestamp string      `xml:"timestamp,attr"`
+	Tests     int         `xml:"tests,attr"`
+	Failures  int         `xml:"failures,attr"`
+	Skipped   int         `xml:"skipped,attr"`
+	Time      float64     `xml:"time,attr"`
+	Cases     []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure"`
+	Skipped   *junitSkipped `xml:"skipped"`
+}
+
+// junitFailure is why a repo failed, with what the failed task wrote to
+// stderr as its text.
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+// junit renders the report as JUnit XML. Repos that were interrupted or not
+// run count as skipped, as they didn't fail.
+func (r runReport) junit() ([]byte, error) {
+	suite := junitSuite{
+		Name:      "git-gc " + strings.Join(r.Tasks, ",") + " " + r.Root,
+		Hostname:  r.Host,
+		Timestamp: r.Started.Format("2006-01-02T15:04:05"),
+		Tests:     len(r.Repos),
+		Time:      r.Duration,
+		Cases:     make([]junitCase, 0, len(r.Repos)),
+	}
+
+	for _, repo := range r.Repos {
+		c := junitCase{Name: repo.Path, Classname: "git-gc." + strings.Join(r.Tasks, "-"), Time: repo.Duration}
+		switch repo.Status {
+		case "failed":
+			suite.Failures++
+			c.Failure = &junitFailure{
+				Message: repo.FailedTask + ": " + repo.Error,
+				Type:    repo.FailedTask,
+				Text:    strings.Join(repo.Stderr, "\n"),
+			}
+		case "interrupted":
+			suite.Skipped++
+			c.Skipped = &junitSkipped{Message: "interrupted during " + repo.FailedTask}
+		case "skipped":
+			suite.Skipped++
+			c.Skipped = &junitSkipped{Message: repo.SkipReason}
+		case "not_run":
+			suite.Skipped++
+			c.Skipped = &junitSkipped{Message: "not run"}
+		}
+
+		suite.Cases = append(suite.Cases, c)
+	}
+
+	out, err := xml.MarshalIndent(junitSuites{
+		Name:     "git-gc",
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Skipped:  suite.Skipped,
+		Time:     suite.Time,
+		Suites:   []junitSuite{suite},
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(append([]byte(xml.Header), out...), '\n'), nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+	"time"
+)
+
+func TestReportJUnit(t *testing.T) {
+	r := runReport{
+		Root:      "/src",
+		Host:      "box",
+		Tasks:     []string{"fetch", "gc"},
+		Started:   time.Date(2024, 3, 5, 2, 0, 0, 0, time.UTC),
+		runTotals: runTotals{Duration: 60},
+		Repos: []repoReport{
+			{Path: "/src/app", Status: "succeeded", Duration: 12.5},
+			{
+				Path:       "/src/lib",
+				Status:     "failed",
+				Duration:   0.5,
+				FailedTask: "fetch",
+				Error:      "exit status 128",
+				Stderr:     []string{"fatal: could not read from remote repository.", "<&> and ]]>"},
+			},
+			{Path: "/src/annex", Status: "skipped", SkipReason: "git-annex repo, pass --annex=safe to include it"},
+			{Path: "/src/big", Status: "interrupted", FailedTask: "gc", Error: "interrupted"},
+			{Path: "/src/huge", Status: "not_run"},
+		},
+	}
+
+	out, err := r.junit()
+	if err != nil {
+		t.Fatalf("junit(): %v", err)
+	}
+
+	if !bytes.HasPrefix(out, []byte(xml.Header)) {
+		t.Errorf("junit() doesn't start with an XML header:\n%s", out)
+	}
+
+	var got junitSuites
+	if err := xml.Unmarshal(out, &got); err != nil {
+		t.Fatalf("reading back\n%s\nfailed: %v", out, err)
+	}
+
+	// Only failed repos are failures: CI shouldn't go red for a run cut short
+	if got.Tests != 5 || got.Failures != 1 || got.Skipped != 3 || len(got.Suites) != 1 {
+		t.Fatalf("junit() = %d tests, %d failures, %d skipped in %d suites, want 5, 1 and 3 in 1", got.Tests, got.Failures, got.Skipped, len(got.Suites))
+	}
+
+	suite := got.Suites[0]
+	if suite.Name != "git-gc fetch,gc /src" || suite.Hostname != "box" || suite.Timestamp != "2024-03-05T02:00:00" || suite.Time != 60 {
+		t.Errorf("suite = %+v, want git-gc fetch,gc /src on box at 2024-03-05T02:00:00 in 60s", suite)
+	}
+
+	cases := make(map[string]junitCase)
+	for _, c := range suite.Cases {
+		cases[c.Name] = c
+	}
+
+	if c := cases["/src/app"]; c.Failure != nil || c.Skipped != nil || c.Time != 12.5 || c.Classname != "git-gc.fetch-gc" {
+		t.Errorf("succeeded repo = %+v", c)
+	}
+
+	want := junitFailure{Message: "fetch: exit status 128", Type: "fetch", Text: "fatal: could not read from remote repository.\n<&> and ]]>"}
+	if c := cases["/src/lib"]; c.Failure == nil || *c.Failure != want {
+		t.Errorf("failed repo has failure %+v, want %+v", c.Failure, want)
+	}
+
+	for path, message := range map[string]string{
+		"/src/annex": "git-annex repo, pass --annex=safe to include it",
+		"/src/big":   "interrupted during gc",
+		"/src/huge":  "not run",
+	} {
+		if c := cases[path]; c.Skipped == nil || c.Skipped.Message != message || c.Failure != nil {
+			t.Errorf("%s = %+v, want it skipped with %q", path, c, message)
+		}
+	}
+}
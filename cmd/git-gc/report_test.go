@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+// TestReportJSON checks the field names tools read, with the totals at the
+// top level and sizes that weren't measured left out.
+func TestReportJSON(t *testing.T) {
+	out, err := json.Marshal(runReport{
+		Root:      "/home/me/src",
+		Tasks:     []string{"gc"},
+		runTotals: runTotals{Duration: 12.5, Succeeded: 1, Skipped: 1, Freed: 1 << 20},
+		Repos: []repoReport{
+			{Path: "/home/me/src/app", Status: "succeeded", Tasks: []string{"gc"}, Duration: 12.5, Before: 3 << 20, After: 2 << 20},
+			{Path: "/home/me/src/shallow", Status: "skipped", Tasks: []string{}, SkipReason: "shallow clone"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got struct {
+		Root      string                       `json:"root"`
+		Succeeded int                          `json:"succeeded"`
+		Freed     int64                        `json:"freed_bytes"`
+		Duration  float64                      `json:"duration_seconds"`
+		Repos     []map[string]json.RawMessage `json:"repos"`
+	}
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Root != "/home/me/src" || got.Succeeded != 1 || got.Freed != 1<<20 || got.Duration != 12.5 {
+		t.Errorf("report totals = %+v, from\n%s", got, out)
+	}
+
+	if len(got.Repos) != 2 {
+		t.Fatalf("report has %d repos, want 2:\n%s", len(got.Repos), out)
+	}
+
+	for _, key := range []string{"path", "status", "tasks", "duration_seconds", "bytes_before", "bytes_after"} {
+		if _, ok := got.Repos[0][key]; !ok {
+			t.Errorf("succeeded repo lacks %q: %s", key, out)
+		}
+	}
+
+	if reason := string(got.Repos[1]["skip_reason"]); reason != `"shallow clone"` {
+		t.Errorf("skipped repo has skip_reason %s, want \"shallow clone\"", reason)
+	}
+
+	for _, key := range []string{"bytes_before", "bytes_after", "failed_task", "error", "stderr"} {
+		if _, ok := got.Repos[1][key]; ok {
+			t.Errorf("skipped repo has %q, want it left out: %s", key, out)
+		}
+	}
+}
+
+// TestReportTable checks that a spreadsheet reads back the rows of the
+// repos, even with separators and quotes in their paths.
+func TestReportTable(t *testing.T) {
+	r := runReport{Repos: []repoReport{
+		{Path: "/src/app", Status: "succeeded", Duration: 12.3456, Before: 3 << 20, After: 2 << 20},
+		{Path: "/src/lib, \"old\"\tcopy", Status: "failed", Duration: 0.5, FailedTask: "fetch", Error: "exit status 128"},
+		{Path: "/src/app-fork", Status: "skipped", SkipReason: "unchanged since the last run"},
+		{Path: "/src/big", Status: "not_run"},
+	}}
+	want := [][]string{
+		{"path", "status", "duration_seconds", "bytes_before", "bytes_after", "freed_bytes", "failed_task", "error"},
+		{"/src/app", "succeeded", "12.346", "3145728", "2097152", "1048576", "", ""},
+		{"/src/lib, \"old\"\tcopy", "failed", "0.500", "", "", "", "fetch", "exit status 128"},
+		{"/src/app-fork", "skipped", "0.000", "", "", "", "", ""},
+		{"/src/big", "not_run", "0.000", "", "", "", "", ""},
+	}
+
+	for _, comma := range []rune{',', '\t'} {
+		out, err := r.table(comma)
+		if err != nil {
+			t.Fatalf("table(%q): %v", comma, err)
+		}
+
+		cr := csv.NewReader(bytes.NewReader(out))
+		cr.Comma = comma
+		got, err := cr.ReadAll()
+		if err != nil {
+			t.Fatalf("reading back\n%s\nfailed: %v", out, err)
+		}
+
+		if !slices.EqualFunc(got, want, slices.Equal[[]string]) {
+			t.Errorf("table(%q) rows = %q, want %q", comma, got, want)
+		}
+	}
+}
+
+func TestMarkdownCode(t *testing.T) {
+	tests := map[string]string{
+		"/src/app":      "`/src/app`",
+		"/src/a`b":      "``/src/a`b``",
+		"/src/a``b`":    "``` /src/a``b` ```",
+		"`/src/app`":    "`` `/src/app` ``",
+		"/src/a|b, c d": "`/src/a|b, c d`",
+	}
+	for in, want := range tests {
+		if got := markdownCode(in); got != want {
+			t.Errorf("markdownCode(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// limitedEnv marks a git-gc that already runs within its resource limits, so
+// it doesn't try to apply them again after re-executing itself.
+const limitedEnv = "GIT_GC_RESOURCE_LIMITED"
+
+// limitResources bounds the memory and CPU time of git-gc and every process
+// it starts, however many subprocesses git forks. It re-executes git-gc in a
+// transient systemd scope if it can, and otherwise moves it into a new cgroup
+// of its own. A zero limit is no limit. The returned func removes what's left
+// to clean up once git-gc's children exited, right before git-gc exits.
+func limitResources(memory int64, cpus float64) (func(), error) {
+	if os.Getenv(limitedEnv) != "" {
+		return func() {}, nil
+	}
+
+	scopeErr := execInScope(memory, cpus)
+	release, err := moveToCgroup(memory, cpus)
+	if err != nil {
+		return nil, fmt.Errorf("%w, and %w", scopeErr, err)
+	}
+
+	return release, nil
+}
+
+// execInScope re-executes git-gc with systemd-run in a transient scope unit
+// with the limits. It only returns if that's not possible.
+func execInScope(memory int64, cpus float64) error {
+	systemdRun, err := exec.LookPath("systemd-run")
+	if err != nil {
+		return errors.New("systemd-run not found")
+	}
+
+	user := os.Geteuid() != 0
+	scope := func(props ...string) []string {
+		argv := []string{systemdRun}
+		if user {
+			argv = append(argv, "--user")
+		}
+
+		argv = append(argv, "--scope", "--quiet", "--collect")
+		for _, p := range props {
+			argv = append(argv, "-p", p)
+		}
+
+		return argv
+	}
+
+	// Without a session to talk to, e.g. under cron, systemd-run fails
+	// before running anything
+	if probe := scope(); exec.Command(probe[0], append(probe[1:], "true")...).Run() != nil {
+		return errors.New("could not create a systemd scope")
+	}
+
+	var props []string
+	if memory > 0 {
+		props = append(props, fmt.Sprintf("MemoryMax=%d", memory))
+	}
+
+	if cpus > 0 {
+		props = append(props, fmt.Sprintf("CPUQuota=%d%%", int(cpus*100)))
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	argv := append(append(scope(props...), "--", self), os.Args[1:]...)
+	return syscall.Exec(argv[0], argv, append(os.Environ(), limitedEnv+"=1"))
+}
+
+// moveToCgroup creates a cgroup v2 next to git-gc's own with the limits, and
+// moves git-gc into it, so its children are born there. The returned func
+// moves git-gc back and removes the cgroup.
+func moveToCgroup(memory int64, cpus float64) (func(), error) {
+	const root = "/sys/fs/cgroup"
+	if _, err := os.Stat(filepath.Join(root, "cgroup.controllers")); err != nil {
+		return nil, errors.New("cgroup v2 is not mounted at " + root)
+	}
+
+	own, err := ownCgroup()
+	if err != nil {
+		return nil, err
+	}
+
+	// Clean up after git-gcs that were killed before removing theirs. Only
+	// empty cgroups can be removed, so the ones in use stay.
+	parent := filepath.Join(root, filepath.Dir(own))
+	if stale, err := filepath.Glob(filepath.Join(parent, "git-gc-*")); err == nil {
+		for _, dir := range stale {
+			_ = os.Remove(dir)
+		}
+	}
+
+	dir := filepath.Join(parent, fmt.Sprintf("git-gc-%d", os.Getpid()))
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("could not create cgroup: %w", err)
+	}
+
+	if err := joinCgroup(dir, parent, memory, cpus); err != nil {
+		_ = os.Remove(dir)
+		return nil, err
+	}
+
+	return func() {
+		// A cgroup can't be removed while git-gc is still in it
+		_ = os.WriteFile(filepath.Join(root, own, "cgroup.procs"), []byte(strconv.Itoa(os.Getpid())), 0)
+		_ = os.Remove(dir)
+	}, nil
+}
+
+// joinCgroup sets the limits of the cgroup at dir, whose parent is parent,
+// and moves git-gc into it.
+func joinCgroup(dir, parent string, memory int64, cpus float64) error {
+	// The controllers may already be enabled, or not be ours to enable
+	_ = os.WriteFile(filepath.Join(parent, "cgroup.subtree_control"), []byte("+memory +cpu"), 0)
+
+	if memory > 0 {
+		if err := os.WriteFile(filepath.Join(dir, "memory.max"), []byte(strconv.FormatInt(memory, 10)), 0); err != nil {
+			return fmt.Errorf("could not limit memory: %w", err)
+		}
+	}
+
+	if cpus > 0 {
+		const period = 100000
+		quota := fmt.Sprintf("%d %d", int(cpus*period), period)
+		if err := os.WriteFile(filepath.Join(dir, "cpu.max"), []byte(quota), 0); err != nil {
+			return fmt.Errorf("could not limit CPU: %w", err)
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "cgroup.procs"), []byte(strconv.Itoa(os.Getpid())), 0); err != nil {
+		return fmt.Errorf("could not join cgroup: %w", err)
+	}
+
+	return nil
+}
+
+// ownCgroup returns the cgroup v2 path of git-gc, e.g. "/user.slice/x.scope".
+func ownCgroup() (string, error) {
+	f, err := os.Open("/proc/self/cgroup")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if path, ok := strings.CutPrefix(sc.Text(), "0::"); ok {
+			return path, nil
+		}
+	}
+
+	return "", errors.New("could not find own cgroup")
+}
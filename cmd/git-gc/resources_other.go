@@ -0,0 +1,11 @@
+//go:build !linux && !windows
+
+package main
+
+import "errors"
+
+// limitResources bounds the memory and CPU time of git-gc and every process
+// it starts, which isn't supported here.
+func limitResources(int64, float64) (func(), error) {
+	return nil, errors.New("not supported on this platform")
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	procCreateJobObject          = kernel32.NewProc("CreateJobObjectW")
+	procSetInformationJobObject  = kernel32.NewProc("SetInformationJobObject")
+	procAssignProcessToJobObject = kernel32.NewProc("AssignProcessToJobObject")
+)
+
+const (
+	jobObjectExtendedLimitInformation  = 9
+	jobObjectCPURateControlInformation = 15
+
+	jobObjectLimitJobMemory = 0x200
+
+	jobObjectCPURateControlEnable  = 0x1
+	jobObjectCPURateControlHardCap = 0x4
+)
+
+// jobExtendedLimitInformation is JOBOBJECT_EXTENDED_LIMIT_INFORMATION.
+type jobExtendedLimitInformation struct {
+	perProcessUserTimeLimit int64
+	perJobUserTimeLimit     int64
+	limitFlags              uint32
+	minimumWorkingSetSize   uintptr
+	maximumWorkingSetSize   uintptr
+	activeProcessLimit      uint32
+	affinity                uintptr
+	priorityClass           uint32
+	schedulingClass         uint32
+	ioInfo                  [6]uint64
+	processMemoryLimit      uintptr
+	jobMemoryLimit          uintptr
+	peakProcessMemoryUsed   uintptr
+	peakJobMemoryUsed       uintptr
+}
+
+// jobCPURateControlInformation is JOBOBJECT_CPU_RATE_CONTROL_INFORMATION.
+type jobCPURateControlInformation struct {
+	controlFlags uint32
+	cpuRate      uint32 // in 1/100 of a percent of all CPUs
+}
+
+// limitResources bounds the memory and CPU time of git-gc and every process
+// it starts, however many subprocesses git forks, by putting git-gc in a job
+// object its children inherit. A zero limit is no limit. There's nothing to
+// clean up with the returned func.
+func limitResources(memory int64, cpus float64) (func(), error) {
+	job, _, err := procCreateJobObject.Call(0, 0)
+	if job == 0 {
+		return nil, fmt.Errorf("could not create job object: %w", err)
+	}
+
+	if memory > 0 {
+		info := jobExtendedLimitInformation{limitFlags: jobObjectLimitJobMemory, jobMemoryLimit: uintptr(memory)}
+		if ok, _, err := procSetInformationJobObject.Call(
+			job, jobObjectExtendedLimitInformation, uintptr(unsafe.Pointer(&info)), unsafe.Sizeof(info),
+		); ok == 0 {
+			return nil, fmt.Errorf("could not limit memory: %w", err)
+		}
+	}
+
+	if cpus > 0 {
+		info := jobCPURateControlInformation{
+			controlFlags: jobObjectCPURateControlEnable | jobObjectCPURateControlHardCap,
+			cpuRate:      uint32(min(10000, max(1, cpus/float64(runtime.NumCPU())*10000))),
+		}
+		if ok, _, err := procSetInformationJobObject.Call(
+			job, jobObjectCPURateControlInformation, uintptr(unsafe.Pointer(&info)), unsafe.Sizeof(info),
+		); ok == 0 {
+			return nil, fmt.Errorf("could not limit CPU: %w", err)
+		}
+	}
+
+	self, _ := syscall.GetCurrentProcess()
+	if ok, _, err := procAssignProcessToJobObject.Call(job, uintptr(self)); ok == 0 {
+		return nil, fmt.Errorf("could not join job object: %w", err)
+	}
+
+	// The job lives as long as its handle, which git-gc keeps until it exits
+	return func() {}, nil
+}
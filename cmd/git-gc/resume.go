@@ -0,0 +1,127 @@
+package main
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// runState is what's known about the last run in a root, so an interrupted
+// run can be resumed.
+type runState struct {
+	Started  time.Time `json:"started"`
+	Finished []string  `json:"finished"` // repos that succeeded, failed or were skipped
+}
+
+// runJournal records the progress of the current run in the state file as
+// repos finish, so it survives a crash or reboot. Saving can wait on other
+// git-gc processes for the state file's lock, so it happens on a goroutine
+// of its own, in batches of whatever finished in the meantime, rather than
+// holding up the UI.
+type runJournal struct {
+	store *stateStore
+	root  string
+
+	mu      sync.Mutex
+	done    []string                 // repos finished since the last save
+	took    map[string]time.Duration // durations to record since the last save
+	pending chan struct{}            // signals the writer that there's something to save
+	closed  chan struct{}            // closed once the writer saved everything
+}
+
+// newRunJournal starts the journal of the run in root. It must be closed
+// once the run is over.
+func newRunJournal(store *stateStore, root string) *runJournal {
+	j := &runJournal{
+		store:   store,
+		root:    root,
+		took:    make(map[string]time.Duration),
+		pending: make(chan struct{}, 1),
+		closed:  make(chan struct{}),
+	}
+
+	go func() {
+		defer close(j.closed)
+		for range j.pending {
+			j.save()
+		}
+		j.save()
+	}()
+
+	return j
+}
+
+// finished records that dir was processed.
+func (j *runJournal) finished(dir string) {
+	if j == nil {
+		return
+	}
+
+	j.mu.Lock()
+	j.done = append(j.done, dir)
+	j.mu.Unlock()
+	j.signal()
+}
+
+// recordTook records how long dir took when it succeeded, for estimating
+// the next runs.
+func (j *runJournal) recordTook(dir string, d time.Duration) {
+	if j == nil {
+		return
+	}
+
+	j.mu.Lock()
+	j.took[dir] = d
+	j.mu.Unlock()
+	j.signal()
+}
+
+func (j *runJournal) signal() {
+	select {
+	case j.pending <- struct{}{}:
+	default:
+		// The writer hasn't picked up the last batch yet, and will take
+		// this one with it
+	}
+}
+
+// save writes what's been recorded since the last save. A failed write
+// only costs redoing repos when resuming and less accurate estimates, so
+// it's ignored.
+func (j *runJournal) save() {
+	j.mu.Lock()
+	done, took := j.done, j.took
+	j.done, j.took = nil, make(map[string]time.Duration)
+	j.mu.Unlock()
+
+	if j.store != nil && (len(done) > 0 || len(took) > 0) {
+		_ = j.store.recordProgress(j.root, done, took)
+	}
+}
+
+// close waits for everything recorded to be saved. Nothing may be recorded
+// after it's called.
+func (j *runJournal) close() {
+	if j == nil {
+		return
+	}
+
+	close(j.pending)
+	<-j.closed
+}
+
+// unfinished returns the dirs the last run in root didn't get to, and whether
+// there was a run to resume.
+func unfinished(st *stateStore, root string, dirs []string) ([]string, bool) {
+	run := st.lastRun(root)
+	if run == nil {
+		return dirs, false
+	}
+
+	finished := make(map[string]bool, len(run.Finished))
+	for _, dir := range run.Finished {
+		finished[dir] = true
+	}
+
+	return slices.DeleteFunc(dirs, func(dir string) bool { return finished[dir] }), true
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"strings"
+	"time"
+)
+
+// transientErrors are what git prints for failures that are likely to go
+// away when the command is run again a little later: another process
+// holding a lock, or a network hiccup.
+var transientErrors = []string{
+	"another git process seems to be running",
+	".lock': File exists",
+	"could not lock config file",
+	"Could not resolve host",
+	"Connection timed out",
+	"Connection reset by peer",
+	"Operation timed out",
+	"The remote end hung up unexpectedly",
+	"early EOF",
+	"RPC failed",
+}
+
+// retryDue is sent when a task that failed transiently is due to be retried.
+type retryDue struct {
+	job job
+}
+
+// isTransient reports whether stderr of a failed command looks like the
+// failure is worth retrying.
+func isTransient(stderr string) bool {
+	for _, s := range transientErrors {
+		if strings.Contains(stderr, s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// retryBackoff returns how long to wait before the given retry, starting at
+// two seconds and doubling up to a minute.
+func retryBackoff(retry int) time.Duration {
+	return min(2*time.Second<<(retry-1), time.Minute)
+}
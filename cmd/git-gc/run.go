@@ -0,0 +1,349 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/template"
+	"time"
+
+	"github.com/charmbracelet/bubbletea"
+)
+
+// runOptions are the settings of a run, as given on the command line.
+type runOptions struct {
+	root     string
+	scan     scanOptions
+	shard    shard
+	order    repoOrder
+	resume   bool
+	limits   [numTaskKinds]int
+	devices  deviceLimits
+	pipeline []task
+	hooks    hooks
+	checks   preflight
+	state    *stateStore
+
+	waitOnQuit   bool
+	waitForLock  bool
+	lowPriority  bool
+	autoParallel bool
+	whenIdle     bool
+	onACOnly     bool
+	repoTimeout  time.Duration
+	retries      int
+	rerun        bool
+	failFast     bool
+	maxRepos     int
+	maxDuration  time.Duration
+	stagger      time.Duration
+	spawnRate    float64
+	spawnBurst   int
+	startJitter  time.Duration
+	hungAfter    time.Duration
+
+	// unattended runs have nobody to answer confirmations, which are
+	// declined.
+	unattended bool
+
+	yes    bool // run without asking for confirmation first
+	dryRun bool // print what would run instead of running it
+
+	// pick shows a screen to pick the repos to run on first, unless there's
+	// nobody to pick them.
+	pick bool
+
+	altScreen bool // show the UI in the terminal's alternate screen
+	title     bool // show the progress in the terminal's title
+	compact   bool // only show the progress bar's line
+
+	// accessible logs plain lines that read well in screen readers, and how
+	// far along the run is now and then; plain has to be set too.
+	accessible bool
+
+	// opener, if set, opens a finished repo when pressing o instead of the
+	// file manager.
+	opener *template.Template
+
+	// control, if set, lets a daemon's clients inspect and pause the run.
+	control *daemonControl
+
+	// porcelain, if set, writes what happens as JSON lines, and tap the
+	// results in TAP.
+	porcelain *porcelain
+	tap       *tapWriter
+
+	// plain, if set, logs a line per repo instead of showing the UI. Nobody
+	// is there to answer confirmations either.
+	plain *log.Logger
+	quiet bool // only the summary is logged, not a line per repo
+
+	verbose bool     // show what commands write to stderr
+	flags   []string // the flags given on the command line, for the help overlay
+	theme   theme    // the colors from the config file
+
+	// logf reports what happens before and after the UI runs.
+	logf func(format string, args ...any)
+}
+
+// run finds the repos under the root and runs the pipeline in them, returning
+// the final model. It fails with an instanceRunningError when another git-gc
+// runs in the same root and opts.waitForLock isn't set.
+func run(opts runOptions, programOpts ...tea.ProgramOption) (model, error) {
+	st := newStyles(opts.theme)
+	if opts.accessible {
+		st = accessibleStyles(st)
+	}
+
+	opts.porcelain.emit(porcelainEvent{Event: "scan-started", Root: opts.root})
+	if opts.control != nil {
+		opts.control.scanStarted()
+	}
+
+	if opts.porcelain != nil {
+		opts.scan.progress = &scanProgress{onFound: func(dir string) {
+			opts.porcelain.emit(porcelainEvent{Event: "repo-found", Path: dir})
+		}}
+	}
+
+	var scan scanResult
+	var err error
+	if opts.plain == nil && !opts.unattended {
+		var ok bool
+		scan, ok, err = scanShowing(opts.root, opts.scan, st)
+		if err == nil && !ok {
+			// Quitting the screen quits the run before it started
+			m := newModel(nil, opts.limits, opts.pipeline, opts.hooks, opts.checks)
+			m.quitting, m.done = true, true
+			return m, nil
+		}
+	} else {
+		scan, err = findDirectories(opts.root, opts.scan)
+	}
+
+	if err != nil {
+		return model{}, fmt.Errorf("finding repos: %w", err)
+	}
+
+	for _, dir := range scan.unresponsive {
+		opts.logf("Skipped %s, listing it took longer than %s\n", dir, opts.scan.dirTimeout)
+	}
+
+	if scan.timedOut {
+		opts.logf("Stopped looking for repos after %s, only processing the %d found so far\n", opts.scan.timeout, len(scan.dirs))
+	}
+
+	m := newModel(scan.dirs, opts.limits, opts.pipeline, opts.hooks, opts.checks)
+	m.styles = st
+	m.spinner.Style = m.styles.start
+
+	m.directories = opts.shard.filter(opts.root, m.directories)
+	sortRepos(m.directories, opts.order)
+
+	// Pick up where a run that ran out of budget left off
+	prioritize(m.directories, opts.state.remaining(opts.root))
+
+	if opts.resume {
+		var ok bool
+		if m.directories, ok = unfinished(opts.state, opts.root, m.directories); !ok {
+			opts.logf("No interrupted run to resume, processing every repo\n")
+		}
+	}
+	if opts.dryRun {
+		printDryRun(os.Stdout, m.directories, m.pipeline, opts.hooks)
+		m.done = true
+		return m, nil
+	}
+
+	if opts.pick && opts.plain == nil && !opts.unattended && len(m.directories) > 0 {
+		dirs, ok, err := pickRepos(m.directories, m.action(), m.styles, opts.altScreen)
+		if err != nil {
+			return model{}, fmt.Errorf("picking repos: %w", err)
+		}
+
+		// Quitting the screen quits the run before it started
+		if !ok {
+			m.quitting, m.done = true, true
+			return m, nil
+		}
+
+		m.directories = dirs
+	}
+
+	m.waitOnQuit = opts.waitOnQuit
+	m.flags = opts.flags
+	m.title, m.compact = opts.title, opts.compact
+	if opts.altScreen && opts.plain == nil {
+		m.altScreen = true
+		programOpts = append(programOpts, tea.WithAltScreen())
+	}
+	m.scan = opts.scan
+	m.porcelain, m.tap = opts.porcelain, opts.tap
+	m.opener = opts.opener
+	m.unattended = opts.unattended
+	m.plain, m.quiet = opts.plain, opts.quiet
+	m.accessible = opts.accessible && opts.plain != nil
+	if opts.plain != nil {
+		m.unattended = true
+		programOpts = append(programOpts, tea.WithInput(nil), tea.WithoutRenderer())
+	}
+	m.devices = opts.devices
+	m.runner.lowPriority = opts.lowPriority
+	if opts.autoParallel || opts.whenIdle || opts.onACOnly {
+		if m.load, err = newLoadMonitor(opts.autoParallel); err != nil {
+			return model{}, fmt.Errorf("enabling --auto-parallel: %w", err)
+		}
+
+		m.load.userIdle, m.load.power = opts.whenIdle, opts.onACOnly
+	}
+
+	m.whenIdle, m.onACOnly = opts.whenIdle, opts.onACOnly
+	if opts.whenIdle {
+		m.idleWait = tr("Checking whether the machine is idle")
+	}
+
+	if opts.onACOnly {
+		m.powerWait = tr("Checking the power source")
+	}
+	m.autoParallel = opts.autoParallel
+	if opts.autoParallel {
+		// Start halfway and let the load decide from there
+		m.maxParallel = m.pools[kindDisk].limit
+		m.pools[kindDisk].limit = max(1, m.maxParallel/2)
+	}
+	m.repoTimeout = opts.repoTimeout
+	m.retries = opts.retries
+	m.rerun = opts.rerun
+	m.failFast = opts.failFast
+	m.budget.maxRepos = opts.maxRepos
+
+	// Nobody can answer without a terminal, e.g. from cron
+	if !opts.yes && !opts.unattended && len(m.directories) > 0 && isTerminal(os.Stdin) {
+		ok, err := confirmRun(m, opts.root, opts.flags)
+		if err != nil {
+			return model{}, fmt.Errorf("confirming the run: %w", err)
+		}
+
+		if !ok {
+			m.quitting, m.done = true, true
+			return m, nil
+		}
+	}
+
+	unlock, err := lockInstance(opts.root)
+	var running *instanceRunningError
+	if opts.waitForLock && errors.As(err, &running) {
+		opts.logf("%s, waiting for it to finish...\n", running)
+		for errors.As(err, &running) {
+			time.Sleep(2 * time.Second)
+			unlock, err = lockInstance(opts.root)
+		}
+	}
+
+	if errors.As(err, &running) {
+		return model{}, err
+	}
+
+	if err != nil {
+		return model{}, fmt.Errorf("locking root: %w", err)
+	}
+	defer unlock()
+
+	m.stagger = opts.stagger
+	m.watchdog.after = opts.hungAfter
+	if opts.spawnRate > 0 {
+		m.spawnRate = newTokenBucket(opts.spawnRate, opts.spawnBurst)
+	}
+	m.startAt = time.Now().Add(jitter(opts.startJitter))
+	if opts.maxDuration > 0 {
+		m.budget.deadline = m.startAt.Add(opts.maxDuration)
+	}
+
+	if err := opts.state.startRun(opts.root, opts.resume); err != nil {
+		return model{}, fmt.Errorf("saving state: %w", err)
+	}
+
+	m.journal = newRunJournal(opts.state, opts.root)
+	m.past = opts.state.durations(m.directories)
+
+	// Signals are handled like quitting, so that the repos still running are
+	// reported and the run can be resumed, instead of bubbletea ending the
+	// program right away
+	programOpts = append(programOpts, tea.WithoutSignalHandler())
+	newProgram := func(paused bool) *tea.Program {
+		m.paused = paused
+		return tea.NewProgram(m, programOpts...)
+	}
+
+	var p *tea.Program
+	if opts.control != nil {
+		p = opts.control.startProgram(newProgram)
+	} else {
+		p = newProgram(false)
+	}
+
+	// Without the UI, lines are logged as they come; the UI prints them
+	// above the progress bar
+	switch {
+	case !opts.verbose:
+	case opts.plain != nil || opts.unattended:
+		m.runner.output = func(dir, line string) { opts.logf("%s: %s\n", dir, line) }
+	default:
+		m.runner.output = func(dir, line string) { p.Send(outputLine{dir: dir, line: line}) }
+	}
+
+	stopSignals := forwardSignals(p)
+	final, err := p.Run()
+	stopSignals()
+	m.runner.close()
+	m.journal.close()
+	if err != nil {
+		return model{}, fmt.Errorf("running program: %w", err)
+	}
+
+	// The summary goes away with the alternate screen
+	fm := final.(model)
+	if fm.altScreen {
+		fmt.Println(fm.View())
+	}
+	if !fm.quitting {
+		if err := opts.state.setRemaining(opts.root, fm.remaining); err != nil {
+			opts.logf("Error saving state: %s\n", err)
+		}
+
+		if err := opts.state.endRun(opts.root); err != nil {
+			opts.logf("Error saving state: %s\n", err)
+		}
+	}
+
+	return fm, nil
+}
+
+// signalled is sent when the process is asked to stop by a signal.
+type signalled struct{}
+
+// forwardSignals sends signalled to p for every SIGINT and SIGTERM, until
+// the returned func is called.
+func forwardSignals(p *tea.Program) func() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-signals:
+				p.Send(signalled{})
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
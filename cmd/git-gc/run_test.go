@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// TestMain runs the program itself when asked to by a test, so that tests
+// can check how the process exits.
+func TestMain(m *testing.M) {
+	if os.Getenv("GIT_GC_TEST_MAIN") == "1" {
+		main()
+		os.Exit(exitOK)
+	}
+
+	os.Exit(m.Run())
+}
+
+func TestInterruptKeepsJournal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs SIGINT and a shell script")
+	}
+
+	realGit, err := exec.LookPath("git")
+	if err != nil {
+		t.Skip("git not found")
+	}
+
+	var (
+		tmp     = t.TempDir()
+		root    = filepath.Join(tmp, "root")
+		started = filepath.Join(tmp, "started")
+		slowGit = filepath.Join(tmp, "git")
+	)
+	if out, err := exec.Command(realGit, "init", "--quiet", filepath.Join(root, "repo")).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v: %s", err, out)
+	}
+
+	// A git whose gc takes long enough to be interrupted
+	script := "#!/bin/sh\nfor arg; do\n\t[ \"$arg\" = gc ] && touch " + started + " && sleep 30\ndone\nexec " + realGit + " \"$@\"\n"
+	if err := os.WriteFile(slowGit, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	// Keep the state file of the run in tmp
+	t.Setenv("HOME", tmp)
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(tmp, "cache"))
+
+	cmd := exec.Command(os.Args[0], "--root", root, "--yes", "--no-tui", "--git", slowGit, "--tasks", "gc")
+	cmd.Env = append(os.Environ(), "GIT_GC_TEST_MAIN=1")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	for deadline := time.Now().Add(10 * time.Second); ; time.Sleep(50 * time.Millisecond) {
+		if _, err := os.Stat(started); err == nil {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			_ = cmd.Process.Kill()
+			t.Fatal("gc didn't start")
+		}
+	}
+
+	if err := cmd.Process.Signal(syscall.SIGINT); err != nil {
+		t.Fatal(err)
+	}
+
+	var exitErr *exec.ExitError
+	if err := cmd.Wait(); !errors.As(err, &exitErr) || exitErr.ExitCode() != exitStopped {
+		t.Fatalf("exited with %v, want exit status %d", err, exitStopped)
+	}
+
+	b, err := os.ReadFile(defaultStatePath())
+	if err != nil {
+		t.Fatalf("reading state: %v", err)
+	}
+
+	var st state
+	if err := json.Unmarshal(b, &st); err != nil {
+		t.Fatalf("parsing state: %v", err)
+	}
+
+	if _, ok := st.Runs[root]; !ok {
+		t.Errorf("run in %s was forgotten, want it kept for --resume", root)
+	}
+}
@@ -0,0 +1,477 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/bubbletea"
+)
+
+// runner runs work on background goroutines and hands the results to the
+// Update loop as messages, so the UI stays responsive while git runs. The
+// model decides what runs when; the runner only executes it.
+type runner struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	results chan tea.Msg
+	closed  chan struct{} // closed once nobody receives results anymore
+
+	mu    sync.Mutex
+	procs map[*os.Process]*procActivity // running child processes, each leading a process group
+	tails map[string]*outputTail        // what the commands of each in-flight repo wrote to stderr last
+
+	lowPriority bool // run commands with reduced CPU and IO priority
+
+	// output, if set, is called with every line a command writes to stderr.
+	output func(dir, line string)
+}
+
+// work is run by the runner, which delivers its result to Update.
+type work func(ctx context.Context) tea.Msg
+
+// killDelay is how long a child process gets to exit after it's asked to
+// terminate before its process group is killed.
+const killDelay = 10 * time.Second
+
+// groupCommand returns a command running argv in dir in its own process
+// group, which ctx stops the way it stops tasks: the command is asked to
+// terminate, and its process group is killed if it's still around after
+// killDelay. The returned func has to be called once the command exited.
+func groupCommand(ctx context.Context, dir string, argv ...string) (*exec.Cmd, func()) {
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Dir = dir
+
+	// Give git a chance to clean up its lock and temporary files
+	exited := make(chan struct{})
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error {
+		go func() {
+			select {
+			case <-time.After(killDelay):
+				_ = killGroup(cmd.Process)
+			case <-exited:
+			}
+		}()
+
+		return terminate(cmd.Process)
+	}
+	cmd.WaitDelay = killDelay + time.Second
+
+	return cmd, func() { close(exited) }
+}
+
+// contextError returns why ctx was done: its cause if it timed out, or
+// errInterrupted if the user quit.
+func contextError(ctx context.Context) error {
+	if cause := context.Cause(ctx); !errors.Is(cause, context.Canceled) {
+		return cause
+	}
+
+	return errInterrupted
+}
+
+func newRunner(parent context.Context) *runner {
+	ctx, cancel := context.WithCancel(parent)
+	return &runner{
+		ctx:     ctx,
+		cancel:  cancel,
+		results: make(chan tea.Msg),
+		closed:  make(chan struct{}),
+		procs:   make(map[*os.Process]*procActivity),
+		tails:   make(map[string]*outputTail),
+	}
+}
+
+// start runs w on its own goroutine.
+func (r *runner) start(w work) {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+
+		msg := w(r.ctx)
+		select {
+		case r.results <- msg:
+		case <-r.closed:
+		}
+	}()
+}
+
+// next waits for the result of the next work to finish. Update has to ask
+// for the next result again after handling one.
+func (r *runner) next() tea.Cmd {
+	return func() tea.Msg {
+		select {
+		case msg := <-r.results:
+			return msg
+		case <-r.closed:
+			return nil
+		}
+	}
+}
+
+// stop cancels the running work, terminating the processes it started. Their
+// results still arrive, as failures.
+func (r *runner) stop() {
+	r.cancel()
+}
+
+// kill cancels the running work and kills the process groups it started
+// right away.
+func (r *runner) kill() {
+	r.cancel()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for p := range r.procs {
+		_ = killGroup(p)
+	}
+}
+
+// suspend stops the processes of the running work until resume, reporting
+// whether that's supported.
+func (r *runner) suspend() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for p := range r.procs {
+		if err := suspendGroup(p); errors.Is(err, errors.ErrUnsupported) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// resume continues the processes suspend stopped. Time spent stopped doesn't
+// count towards looking hung.
+func (r *runner) resume() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for p, a := range r.procs {
+		_ = resumeGroup(p)
+		a.touch()
+	}
+}
+
+// close stops the running work and waits for it to return, dropping its
+// results. It must be called once the program exits.
+func (r *runner) close() {
+	r.stop()
+	close(r.closed)
+	r.wg.Wait()
+}
+
+// track keeps p around to kill it with the rest, until untrack is called.
+func (r *runner) track(p *os.Process, a *procActivity) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.procs[p] = a
+}
+
+func (r *runner) untrack(p *os.Process) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.procs, p)
+}
+
+// hung returns the commands that neither wrote output nor used CPU time for
+// after, longest idle first.
+func (r *runner) hung(after time.Duration) []hungTask {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var hung []hungTask
+	for p, a := range r.procs {
+		if cpu, ok := groupCPUTime(p.Pid); ok && cpu > a.cpu {
+			a.cpu = cpu
+			a.touch()
+		}
+
+		if idle := a.idle(); idle >= after && a.killed.Load() == nil {
+			hung = append(hung, hungTask{dir: a.dir, idle: idle})
+		}
+	}
+
+	slices.SortFunc(hung, func(a, b hungTask) int { return cmp.Compare(b.idle, a.idle) })
+	return hung
+}
+
+// killHung kills the process group of the command running in dir, which
+// then reports that it was skipped.
+func (r *runner) killHung(dir string) {
+	r.skip(dir, "killed because it looked hung")
+}
+
+// skip stops the command running in dir, which then reports that it was
+// skipped for reason. Like a stopped task, it's asked to terminate, and its
+// process group is killed if it's still around after killDelay. It reports
+// whether a command was running.
+func (r *runner) skip(dir, reason string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var found bool
+	for p, a := range r.procs {
+		if a.dir == dir {
+			a.killed.Store(&reason)
+			_ = terminate(p)
+			time.AfterFunc(killDelay, func() { r.killIfRunning(p) })
+			found = true
+		}
+	}
+
+	return found
+}
+
+// killIfRunning kills the process group of p unless p exited already.
+func (r *runner) killIfRunning(p *os.Process) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.procs[p]; ok {
+		_ = killGroup(p)
+	}
+}
+
+// runTask runs a pipeline step of a repo. The first step is preceded by the
+// preflight checks and the pre hook.
+func (m model) runTask(j job) work {
+	var (
+		dir, step = j.dir, j.step
+		t         = m.pipeline[step]
+		checks    = m.checks
+		pre       = m.hooks.pre
+		deadline  time.Time
+		retry     = m.attempts[dir] > 0
+	)
+	if m.repoTimeout > 0 {
+		deadline = m.started[dir].Add(m.repoTimeout)
+	}
+
+	withDeadline := func(ctx context.Context) (context.Context, context.CancelFunc) {
+		if deadline.IsZero() {
+			return ctx, func() {}
+		}
+
+		return context.WithDeadlineCause(ctx, deadline, fmt.Errorf("repo timed out after %s", m.repoTimeout))
+	}
+
+	// Remember what a repo looks like once its whole pipeline succeeded
+	recorded := func(ctx context.Context, done taskCompleted) taskCompleted {
+		if done.err == nil && done.skip == "" && step == len(m.pipeline)-1 {
+			checks.recordFingerprint(ctx, dir)
+			done.sizeAfter = gitDirSize(ctx, dir)
+		}
+
+		return done
+	}
+
+	if j.inv != nil {
+		inv := *j.inv
+		return func(ctx context.Context) tea.Msg {
+			ctx, cancel := withDeadline(ctx)
+			defer cancel()
+
+			if err := prepareCommand(ctx, &inv); err != nil {
+				return taskCompleted{dir: dir, step: step, note: inv.note, err: err}
+			}
+
+			return recorded(ctx, m.runner.runCommand(ctx, dir, inv, step, t.timeout))
+		}
+	}
+
+	return func(ctx context.Context) (msg tea.Msg) {
+		ctx, cancel := withDeadline(ctx)
+		defer cancel()
+
+		var prepared string
+		if step == 0 && !retry {
+			res, err := checks.run(ctx, dir)
+			if err != nil {
+				return taskCompleted{dir: dir, step: step, err: err}
+			}
+
+			if res.skip != "" {
+				return taskCompleted{dir: dir, step: step, skip: res.skip}
+			}
+
+			prepared = res.note
+
+			if err := runHook(ctx, pre, dir, hookData{}); err != nil {
+				return taskCompleted{dir: dir, step: step, note: prepared, err: err}
+			}
+
+			// Measured after the preflight checks, which may unshallow, to
+			// see what the tasks themselves freed
+			before := gitDirSize(ctx, dir)
+			defer func() {
+				if done, ok := msg.(taskCompleted); ok {
+					done.sizeBefore = before
+					msg = done
+				}
+			}()
+		}
+
+		inv, err := t.command(ctx, dir)
+		if err != nil {
+			return taskCompleted{dir: dir, step: step, note: prepared, err: err}
+		}
+
+		inv.note = joinNotes(prepared, inv.note)
+
+		if inv.argv == nil {
+			return recorded(ctx, taskCompleted{dir: dir, step: step, note: inv.note})
+		}
+
+		if inv.confirm != "" {
+			return confirmRequest{job: j, inv: inv}
+		}
+
+		if err := prepareCommand(ctx, &inv); err != nil {
+			return taskCompleted{dir: dir, step: step, note: inv.note, err: err}
+		}
+
+		return recorded(ctx, m.runner.runCommand(ctx, dir, inv, step, t.timeout))
+	}
+}
+
+// errInterrupted is the error of tasks stopped because the user quit.
+var errInterrupted = errors.New("interrupted")
+
+// joinNotes joins the non-empty notes into one.
+func joinNotes(notes ...string) string {
+	return strings.Join(slices.DeleteFunc(notes, func(s string) bool { return s == "" }), "; ")
+}
+
+// prepareCommand calls inv's beforeRun, if any, adding its note to inv's.
+func prepareCommand(ctx context.Context, inv *invocation) error {
+	if inv.beforeRun == nil {
+		return nil
+	}
+
+	note, err := inv.beforeRun(ctx)
+	inv.note = joinNotes(inv.note, note)
+	return err
+}
+
+// runCommand runs inv in dir and waits for it to exit, stopping it after
+// timeout if that's not zero. A stopped command is asked to terminate, and
+// its process group is killed if it's still around after killDelay.
+func (r *runner) runCommand(ctx context.Context, dir string, inv invocation, step int, timeout time.Duration) taskCompleted {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, timeout, fmt.Errorf("timed out after %s", timeout))
+		defer cancel()
+	}
+
+	cmd, exited := groupCommand(ctx, dir, inv.argv...)
+	if len(inv.env) > 0 {
+		cmd.Env = append(os.Environ(), inv.env...)
+	}
+
+	stderr := &bytes.Buffer{}
+	activity := newProcActivity(dir)
+	cmd.Stdout = activityWriter{io.Discard, activity}
+	cmd.Stderr = activityWriter{io.MultiWriter(stderr, r.tail(dir)), activity}
+
+	var lines *lineWriter
+	if r.output != nil {
+		lines = &lineWriter{emit: func(line string) { r.output(dir, line) }}
+		cmd.Stderr = activityWriter{io.MultiWriter(stderr, r.tail(dir), lines), activity}
+	}
+
+	start := cmd.Start
+	if r.lowPriority {
+		start = func() error { return startLowPriority(cmd) }
+	}
+
+	err := start()
+	if err == nil {
+		r.track(cmd.Process, activity)
+		err = cmd.Wait()
+		r.untrack(cmd.Process)
+	}
+	exited()
+
+	if lines != nil {
+		lines.flush()
+	}
+
+	if reason := activity.killed.Load(); reason != nil {
+		return taskCompleted{dir: dir, step: step, note: inv.note, skip: *reason}
+	}
+
+	if err != nil {
+		if ctx.Err() != nil {
+			return taskCompleted{dir: dir, step: step, note: inv.note, err: contextError(ctx)}
+		}
+
+		return taskCompleted{
+			dir:       dir,
+			step:      step,
+			note:      inv.note,
+			err:       gitError(err, stderr.String()),
+			transient: isTransient(stderr.String()),
+			output:    lastLines(stderr.String(), excerptLines),
+		}
+	}
+
+	note := inv.note
+	if inv.onSuccess != nil {
+		more, err := inv.onSuccess(ctx, stderr.String())
+		note = joinNotes(note, more)
+		if err != nil {
+			return taskCompleted{dir: dir, step: step, note: note, err: err}
+		}
+	}
+
+	return taskCompleted{dir: dir, step: step, note: note}
+}
+
+// excerptLines is how many lines of a failed command's stderr are shown
+// under its repo's completion line.
+const excerptLines = 5
+
+// lastLines returns the last n non-empty lines of stderr, with lines redrawn
+// by progress meters in their final state.
+func lastLines(stderr string, n int) []string {
+	var lines []string
+	w := &lineWriter{emit: func(line string) { lines = append(lines, line) }}
+	_, _ = w.Write([]byte(stderr))
+	w.flush()
+
+	return lines[max(0, len(lines)-n):]
+}
+
+// gitError annotates a failed git command with the most relevant line it
+// wrote to stderr: the last fatal/error line, or else the last line.
+func gitError(err error, stderr string) error {
+	// Progress meters redraw their line, which a fatal error can end up on
+	lines := strings.Split(strings.TrimSpace(stderr), "\n")
+	for i, line := range lines {
+		lines[i] = lastSegment([]byte(line))
+	}
+
+	reason := lines[len(lines)-1]
+	for _, line := range slices.Backward(lines) {
+		if strings.HasPrefix(line, "fatal: ") || strings.HasPrefix(line, "error: ") {
+			reason = line
+			break
+		}
+	}
+
+	if reason != "" {
+		return fmt.Errorf("%w: %s", err, reason)
+	}
+
+	return err
+}
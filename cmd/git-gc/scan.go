@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ugurcsen/gods-generic/sets/hashset"
+)
+
+// scanOptions bound how long finding the repos may take, so a wedged network
+// mount can't hang the program.
+type scanOptions struct {
+	timeout    time.Duration // for the whole scan, 0 for no limit
+	dirTimeout time.Duration // for listing a single directory, 0 for no limit
+
+	progress *scanProgress // updated as the scan goes, if set
+}
+
+// scanResult is what findDirectories found.
+type scanResult struct {
+	dirs         []string // repos, sorted
+	unresponsive []string // directories skipped because listing them took too long
+	timedOut     bool     // whether the scan stopped early because of its timeout
+}
+
+// errScanTimedOut stops the walk once the whole scan took too long.
+var errScanTimedOut = errors.New("scan timed out")
+
+func findDirectories(rootDir string, opts scanOptions) (scanResult, error) {
+	root, err := filepath.Abs(os.ExpandEnv(rootDir))
+	if err != nil {
+		return scanResult{}, fmt.Errorf("could not determine absolute path for root dir: %w", err)
+	}
+
+	fi, err := os.Stat(root)
+	if err != nil {
+		return scanResult{}, fmt.Errorf("could not stat root dir: %w", err)
+	}
+
+	if !fi.IsDir() {
+		return scanResult{}, errors.New("root dir '" + root + "' is not a directory")
+	}
+
+	ctx, cancel := context.Background(), context.CancelFunc(func() {})
+	if opts.timeout > 0 {
+		ctx, cancel = context.WithTimeoutCause(ctx, opts.timeout, errScanTimedOut)
+	}
+	defer cancel()
+
+	s := &scanner{ctx: ctx, dirTimeout: opts.dirTimeout, progress: opts.progress, dirs: hashset.New[string]()}
+	err = s.walk(root)
+	if err != nil && !errors.Is(err, errScanTimedOut) {
+		return scanResult{}, err
+	}
+
+	dirsSlice := s.dirs.Values()
+	slices.Sort(dirsSlice)
+	return scanResult{dirs: dirsSlice, unresponsive: s.unresponsive, timedOut: err != nil}, nil
+}
+
+// scanner walks a directory tree looking for repos, giving up on directories
+// that don't respond in time.
+type scanner struct {
+	ctx          context.Context
+	dirTimeout   time.Duration
+	progress     *scanProgress
+	dirs         *hashset.Set[string]
+	unresponsive []string
+}
+
+// listing is what's read of a single directory.
+type listing struct {
+	entries []os.DirEntry
+	isRepo  bool
+}
+
+func (s *scanner) walk(dir string) error {
+	s.progress.listing(dir)
+	l, err := s.list(dir)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			s.unresponsive = append(s.unresponsive, dir)
+			return nil
+		}
+
+		if os.IsPermission(err) {
+			return nil
+		}
+
+		return err
+	}
+
+	if l.isRepo && !strings.HasPrefix(filepath.Base(dir), ".") {
+		s.dirs.Add(dir)
+		s.progress.foundRepo(dir)
+	}
+
+	for _, e := range l.entries {
+		// Like filepath.Walk, symlinks aren't followed
+		if e.IsDir() {
+			if err := s.walk(filepath.Join(dir, e.Name())); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// list reads dir, or gives up once dirTimeout passed or the scan timed out.
+// A hung read is left behind, as there's no way to interrupt it.
+func (s *scanner) list(dir string) (listing, error) {
+	type result struct {
+		l   listing
+		err error
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		entries, err := os.ReadDir(dir)
+		_, gitErr := os.Stat(filepath.Join(dir, ".git"))
+		done <- result{listing{entries: entries, isRepo: gitErr == nil}, err}
+	}()
+
+	var timeout <-chan time.Time
+	if s.dirTimeout > 0 {
+		t := time.NewTimer(s.dirTimeout)
+		defer t.Stop()
+		timeout = t.C
+	}
+
+	select {
+	case r := <-done:
+		return r.l, r.err
+	case <-timeout:
+		return listing{}, context.DeadlineExceeded
+	case <-s.ctx.Done():
+		return listing{}, context.Cause(s.ctx)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"math/rand/v2"
+	"time"
+
+	"github.com/charmbracelet/bubbletea"
+)
+
+// spawnDue is sent when tasks held off by --stagger, --spawn-rate or
+// --start-jitter may start.
+type spawnDue struct{}
+
+// spawnAfter sends spawnDue after d.
+func spawnAfter(d time.Duration) tea.Cmd {
+	return tea.Tick(max(d, time.Millisecond), func(time.Time) tea.Msg { return spawnDue{} })
+}
+
+// tokenBucket limits how fast tasks start: it holds up to burst tokens,
+// refills rate of them per second, and every task takes one. Starting with a
+// single token makes the load ramp up instead of spiking.
+type tokenBucket struct {
+	rate   float64 // tokens per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	return &tokenBucket{rate: rate, burst: float64(max(1, burst)), tokens: 1, last: time.Now()}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+}
+
+// take takes a token if there's one.
+func (b *tokenBucket) take(now time.Time) bool {
+	b.refill(now)
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+	return true
+}
+
+// wait returns how long until the next token is available.
+func (b *tokenBucket) wait(now time.Time) time.Duration {
+	b.refill(now)
+	if b.tokens >= 1 {
+		return 0
+	}
+
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// jitter returns a random duration up to max, so machines started by the
+// same schedule spread out.
+func jitter(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+
+	return rand.N(max)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	steps := []struct {
+		at       time.Duration // since start
+		wantWait time.Duration
+		wantTake bool
+	}{
+		{at: 0, wantWait: 0, wantTake: true}, // the first token is there right away
+		{at: 0, wantWait: time.Second, wantTake: false},
+		{at: 500 * time.Millisecond, wantWait: 500 * time.Millisecond, wantTake: false},
+		{at: time.Second, wantWait: 0, wantTake: true},
+		// Idling fills the bucket up to burst only
+		{at: time.Minute, wantWait: 0, wantTake: true},
+		{at: time.Minute, wantWait: 0, wantTake: true},
+		{at: time.Minute, wantWait: time.Second, wantTake: false},
+	}
+
+	b := &tokenBucket{rate: 1, burst: 2, tokens: 1, last: start}
+	for i, step := range steps {
+		now := start.Add(step.at)
+		if got := b.wait(now); got != step.wantWait {
+			t.Errorf("step %d: wait at %s = %s, want %s", i, step.at, got, step.wantWait)
+		}
+
+		if got := b.take(now); got != step.wantTake {
+			t.Errorf("step %d: take at %s = %t, want %t", i, step.at, got, step.wantTake)
+		}
+	}
+}
+
+func TestNewTokenBucketBurst(t *testing.T) {
+	// A burst under 1 would never let a task start
+	if b := newTokenBucket(1, 0); b.burst != 1 || !b.take(b.last) {
+		t.Errorf("newTokenBucket(1, 0) = %+v, want a burst of 1 and a token to start with", *b)
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// period is how often scheduled runs happen: a calendar period, which
+// schedulers can catch up on after the machine was off, or a fixed interval.
+type period struct {
+	name  string // hourly, daily, weekly or monthly; "" for a fixed interval
+	every time.Duration
+}
+
+var periodNames = []string{"hourly", "daily", "weekly", "monthly"}
+
+// parsePeriod parses one of periodNames or an interval as parseInterval
+// accepts it, e.g. "12h" or "2w".
+func parsePeriod(s string) (period, error) {
+	switch s {
+	case "hourly":
+		return period{name: s, every: time.Hour}, nil
+	case "daily":
+		return period{name: s, every: 24 * time.Hour}, nil
+	case "weekly":
+		return period{name: s, every: 7 * 24 * time.Hour}, nil
+	case "monthly":
+		return period{name: s, every: 30 * 24 * time.Hour}, nil
+	}
+
+	every, err := parseInterval(s)
+	if err != nil || every < time.Minute {
+		return period{}, fmt.Errorf("invalid period %q (available: %s, or an interval of at least 1m)", s, strings.Join(periodNames, ", "))
+	}
+
+	return period{every: every}, nil
+}
+
+func (p period) String() string {
+	return cmp.Or(p.name, "every "+p.every.String())
+}
+
+// scheduledRun is what git-gc schedule install sets up the platform's
+// scheduler to run.
+type scheduledRun struct {
+	argv     []string // git-gc's absolute path first
+	root     string
+	period   period
+	whenIdle bool // --when-idle is among the flags
+}
+
+// scheduleActions are the subcommands of git-gc schedule.
+var scheduleActions = []string{"install", "uninstall"}
+
+// newScheduledRun returns a run in root with the flags given on the command
+// line, apart from those only about scheduling.
+func newScheduledRun(root string, p period) (scheduledRun, error) {
+	exe, err := os.Executable()
+	if err == nil {
+		exe, err = filepath.EvalSymlinks(exe)
+	}
+
+	if err != nil {
+		return scheduledRun{}, fmt.Errorf("could not determine the path of git-gc: %w", err)
+	}
+
+	// Scheduled runs have no terminal, and log like the daemon instead
+	argv := []string{exe, "daemon", "--once"}
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "every" || f.Name == "root" || f.Name == "once" {
+			return
+		}
+
+		value, absErr := absFlagValue(f.Name, f.Value.String())
+		err = cmp.Or(err, absErr)
+		argv = append(argv, fmt.Sprintf("--%s=%s", f.Name, value))
+	})
+
+	if err != nil {
+		return scheduledRun{}, err
+	}
+
+	argv = append(argv, "--root="+root)
+	for _, arg := range argv {
+		if strings.ContainsAny(arg, "\n\r\x00") {
+			return scheduledRun{}, fmt.Errorf("argument %q can't be scheduled", arg)
+		}
+	}
+
+	idle := flag.Lookup("when-idle")
+	return scheduledRun{argv: argv, root: root, period: p, whenIdle: idle != nil && idle.Value.String() == "true"}, nil
+}
+
+// pathFlags are the flags whose values are paths, which the scheduler would
+// otherwise resolve against its own working directory, such as $HOME or
+// System32, instead of the one schedule install ran in.
+var pathFlags = []string{"backup-dir", "config", "git", "report"}
+
+// absFlagValue returns the value of the flag called name with the paths in
+// it made absolute.
+func absFlagValue(name, value string) (string, error) {
+	switch {
+	case value == "":
+		return value, nil
+	case name == "parallel-device":
+		pairs := strings.Split(value, ",")
+		for i, pair := range pairs {
+			path, limit, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if !ok {
+				continue
+			}
+
+			abs, err := filepath.Abs(path)
+			if err != nil {
+				return "", fmt.Errorf("could not resolve --%s %s: %w", name, path, err)
+			}
+
+			pairs[i] = abs + "=" + limit
+		}
+
+		return strings.Join(pairs, ","), nil
+	case !slices.Contains(pathFlags, name):
+		return value, nil
+	case name == "git" && !strings.ContainsAny(value, `/\`):
+		// A name, looked up in PATH
+		return value, nil
+	}
+
+	abs, err := filepath.Abs(value)
+	if err != nil {
+		return "", fmt.Errorf("could not resolve --%s %s: %w", name, value, err)
+	}
+
+	return abs, nil
+}
+
+// runSchedule carries out git-gc schedule ACTION and returns the exit code.
+func runSchedule(action, root string, p period) int {
+	var (
+		done string
+		err  error
+	)
+	switch action {
+	case "install":
+		var run scheduledRun
+		if run, err = newScheduledRun(root, p); err == nil {
+			done, err = installSchedule(run)
+		}
+	case "uninstall":
+		done, err = uninstallSchedule()
+	default:
+		err = errors.New("expected git-gc schedule install or git-gc schedule uninstall")
+	}
+
+	if err != nil {
+		fmt.Println("Error:", err)
+		return exitError
+	}
+
+	fmt.Println(done)
+	return exitOK
+}
+
+// checkXML checks that doc is well-formed XML.
+func checkXML(doc []byte) error {
+	d := xml.NewDecoder(bytes.NewReader(doc))
+	for {
+		_, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func xmlEscape(s string) (string, error) {
+	var b strings.Builder
+	err := xml.EscapeText(&b, []byte(s))
+	return b.String(), err
+}
+
+// scheduleAction returns the action of git-gc schedule ACTION, taking it off
+// args.
+func scheduleAction(args []string) (string, []string) {
+	if len(args) > 0 && slices.Contains(scheduleActions, args[0]) {
+		return args[0], args[1:]
+	}
+
+	return "", args
+}
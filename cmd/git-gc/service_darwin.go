@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// launchdLabel is the label of the launchd agent running git-gc.
+const launchdLabel = "io.github.kellen-miller.git-gc"
+
+var launchdAgent = template.Must(template.New("agent").Funcs(template.FuncMap{"xml": xmlEscape}).Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>{{xml .Label}}</string>
+	<key>ProgramArguments</key>
+	<array>
+	{{- range .Argv}}
+		<string>{{xml .}}</string>
+	{{- end}}
+	</array>
+	<key>EnvironmentVariables</key>
+	<dict>
+		<key>PATH</key>
+		<string>{{xml .Path}}</string>
+	</dict>
+	{{- if .Calendar}}
+	<key>StartCalendarInterval</key>
+	<dict>
+	{{- range $key, $value := .Calendar}}
+		<key>{{$key}}</key>
+		<integer>{{$value}}</integer>
+	{{- end}}
+	</dict>
+	{{- else}}
+	<key>StartInterval</key>
+	<integer>{{.Seconds}}</integer>
+	{{- end}}
+	<key>ProcessType</key>
+	<string>Background</string>
+	<key>StandardOutPath</key>
+	<string>{{xml .Log}}</string>
+	<key>StandardErrorPath</key>
+	<string>{{xml .Log}}</string>
+</dict>
+</plist>
+`))
+
+// launchdCalendars are the StartCalendarInterval of each period name, at
+// the same times as systemd's.
+var launchdCalendars = map[string]map[string]int{
+	"hourly":  {"Minute": 0},
+	"daily":   {"Hour": 0, "Minute": 0},
+	"weekly":  {"Weekday": 1, "Hour": 0, "Minute": 0},
+	"monthly": {"Day": 1, "Hour": 0, "Minute": 0},
+}
+
+// installSchedule writes and loads a launchd agent starting run.
+func installSchedule(run scheduledRun) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("could not determine the home directory: %w", err)
+	}
+
+	path := filepath.Join(home, "Library", "LaunchAgents", launchdLabel+".plist")
+	logPath := filepath.Join(home, "Library", "Logs", "git-gc.log")
+	agent, err := renderLaunchdAgent(run, logPath)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", fmt.Errorf("could not create %s: %w", filepath.Dir(path), err)
+	}
+
+	if err := os.WriteFile(path, agent, 0o644); err != nil {
+		return "", fmt.Errorf("could not write %s: %w", path, err)
+	}
+
+	if out, err := exec.Command("plutil", "-lint", path).CombinedOutput(); err != nil {
+		_ = os.Remove(path)
+		return "", fmt.Errorf("invalid launchd agent: %s", strings.TrimSpace(string(out)))
+	}
+
+	// Replace the agent of an earlier install, which may still be loaded
+	_ = exec.Command("launchctl", "bootout", launchdDomain(), path).Run()
+	if out, err := exec.Command("launchctl", "bootstrap", launchdDomain(), path).CombinedOutput(); err != nil {
+		return "", fmt.Errorf("launchctl bootstrap: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	return fmt.Sprintf("Installed %s, running %s and logging to %s", path, run.period, logPath), nil
+}
+
+// uninstallSchedule unloads and removes the launchd agent.
+func uninstallSchedule() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("could not determine the home directory: %w", err)
+	}
+
+	path := filepath.Join(home, "Library", "LaunchAgents", launchdLabel+".plist")
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		return "", errors.New("no schedule is installed")
+	}
+
+	_ = exec.Command("launchctl", "bootout", launchdDomain(), path).Run()
+	if err := os.Remove(path); err != nil {
+		return "", fmt.Errorf("could not remove %s: %w", path, err)
+	}
+
+	return "Uninstalled the git-gc launchd agent", nil
+}
+
+// launchdDomain is the launchd domain of the logged in user's agents.
+func launchdDomain() string {
+	return fmt.Sprintf("gui/%d", os.Getuid())
+}
+
+// renderLaunchdAgent renders the property list of the agent for run, and
+// checks that it came out as well-formed XML.
+func renderLaunchdAgent(run scheduledRun, logPath string) ([]byte, error) {
+	// Agents start with a minimal PATH, which misses git and the tools hooks
+	// run if they're installed with Homebrew
+	var agent bytes.Buffer
+	if err := launchdAgent.Execute(&agent, map[string]any{
+		"Label":    launchdLabel,
+		"Argv":     run.argv,
+		"Path":     os.Getenv("PATH"),
+		"Calendar": launchdCalendars[run.period.name],
+		"Seconds":  int64(run.period.every.Seconds()),
+		"Log":      logPath,
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := checkXML(agent.Bytes()); err != nil {
+		return nil, fmt.Errorf("invalid launchd agent: %w", err)
+	}
+
+	return agent.Bytes(), nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderLaunchdAgent(t *testing.T) {
+	tests := []struct {
+		name string
+		run  scheduledRun
+		log  string
+		want []string
+	}{
+		{
+			name: "daily",
+			run: scheduledRun{
+				argv:   []string{"/usr/local/bin/git-gc", "--root", "/Users/me/src"},
+				root:   "/Users/me/src",
+				period: period{name: "daily", every: 24 * time.Hour},
+			},
+			log: "/Users/me/Library/Logs/git-gc.log",
+			want: []string{
+				"<string>/usr/local/bin/git-gc</string>\n\t\t<string>--root</string>\n\t\t<string>/Users/me/src</string>\n",
+				"<key>StartCalendarInterval</key>\n\t<dict>\n\t\t<key>Hour</key>\n\t\t<integer>0</integer>\n\t\t<key>Minute</key>\n\t\t<integer>0</integer>\n",
+				"<key>StandardOutPath</key>\n\t<string>/Users/me/Library/Logs/git-gc.log</string>\n",
+			},
+		},
+		{
+			name: "weekly",
+			run: scheduledRun{
+				argv:   []string{"/usr/local/bin/git-gc"},
+				period: period{name: "weekly", every: 7 * 24 * time.Hour},
+			},
+			want: []string{"<key>Weekday</key>\n\t\t<integer>1</integer>\n"},
+		},
+		{
+			name: "interval",
+			run: scheduledRun{
+				argv:   []string{"/usr/local/bin/git-gc"},
+				period: period{every: 6 * time.Hour},
+			},
+			want: []string{"<key>StartInterval</key>\n\t<integer>21600</integer>\n"},
+		},
+		{
+			name: "escaping",
+			run: scheduledRun{
+				argv:   []string{"/Applications/R&D Tools/git-gc", "--root", `/Users/me/<"src">`},
+				period: period{name: "daily", every: 24 * time.Hour},
+			},
+			log: "/tmp/a&b.log",
+			want: []string{
+				"<string>/Applications/R&amp;D Tools/git-gc</string>",
+				"<string>/Users/me/&lt;&#34;src&#34;&gt;</string>",
+				"<string>/tmp/a&amp;b.log</string>",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agent, err := renderLaunchdAgent(tt.run, tt.log)
+			if err != nil {
+				t.Fatalf("renderLaunchdAgent(): %v", err)
+			}
+
+			for _, want := range tt.want {
+				if !strings.Contains(string(agent), want) {
+					t.Errorf("agent\n%s\nlacks %q", agent, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// systemdUnit is the name of the systemd user service and timer running
+// git-gc.
+const systemdUnit = "git-gc"
+
+var systemdService = template.Must(template.New("service").Parse(`[Unit]
+Description=Run git gc in every repository under {{.Root}}
+
+[Service]
+Type=oneshot
+ExecStart={{.ExecStart}}
+`))
+
+var systemdTimer = template.Must(template.New("timer").Parse(`[Unit]
+Description=Run git-gc {{.Period}}
+
+[Timer]
+{{- if .Calendar}}
+OnCalendar={{.Calendar}}
+Persistent=true
+{{- else}}
+OnBootSec=15min
+OnUnitActiveSec={{.Seconds}}s
+{{- end}}
+
+[Install]
+WantedBy=timers.target
+`))
+
+// installSchedule writes and enables a systemd user timer starting run.
+func installSchedule(run scheduledRun) (string, error) {
+	dir, err := systemdUserDir()
+	if err != nil {
+		return "", err
+	}
+
+	service, timer, err := renderSystemdUnits(run)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("could not create %s: %w", dir, err)
+	}
+
+	servicePath := filepath.Join(dir, systemdUnit+".service")
+	timerPath := filepath.Join(dir, systemdUnit+".timer")
+	for path, unit := range map[string][]byte{servicePath: service, timerPath: timer} {
+		if err := os.WriteFile(path, unit, 0o644); err != nil {
+			return "", fmt.Errorf("could not write %s: %w", path, err)
+		}
+	}
+
+	// Catch what only systemd knows about, such as an unknown calendar
+	// expression, before enabling anything
+	if analyze, err := exec.LookPath("systemd-analyze"); err == nil {
+		if out, err := exec.Command(analyze, "verify", servicePath, timerPath).CombinedOutput(); err != nil {
+			_ = os.Remove(servicePath)
+			_ = os.Remove(timerPath)
+			return "", fmt.Errorf("invalid systemd units: %s", strings.TrimSpace(string(out)))
+		}
+	}
+
+	if err := systemctl("daemon-reload"); err != nil {
+		return "", err
+	}
+
+	if err := systemctl("enable", "--now", systemdUnit+".timer"); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("Installed %s and %s, running %s; see systemctl --user list-timers", servicePath, timerPath, run.period), nil
+}
+
+// uninstallSchedule disables and removes the systemd user timer.
+func uninstallSchedule() (string, error) {
+	dir, err := systemdUserDir()
+	if err != nil {
+		return "", err
+	}
+
+	timerPath := filepath.Join(dir, systemdUnit+".timer")
+	if _, err := os.Stat(timerPath); errors.Is(err, fs.ErrNotExist) {
+		return "", errors.New("no schedule is installed")
+	}
+
+	if err := systemctl("disable", "--now", systemdUnit+".timer"); err != nil {
+		return "", err
+	}
+
+	for _, path := range []string{timerPath, filepath.Join(dir, systemdUnit+".service")} {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return "", fmt.Errorf("could not remove %s: %w", path, err)
+		}
+	}
+
+	if err := systemctl("daemon-reload"); err != nil {
+		return "", err
+	}
+
+	return "Uninstalled the git-gc timer", nil
+}
+
+func systemdUserDir() (string, error) {
+	config, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("could not determine the systemd user unit directory: %w", err)
+	}
+
+	return filepath.Join(config, "systemd", "user"), nil
+}
+
+func systemctl(args ...string) error {
+	out, err := exec.Command("systemctl", append([]string{"--user"}, args...)...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("systemctl --user %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
+
+// renderSystemdUnits renders the service and timer units for run, and
+// checks that they came out as the key=value lines systemd expects.
+func renderSystemdUnits(run scheduledRun) ([]byte, []byte, error) {
+	argv := make([]string, len(run.argv))
+	for i, arg := range run.argv {
+		argv[i] = systemdQuote(arg)
+	}
+
+	var service, timer bytes.Buffer
+	if err := systemdService.Execute(&service, map[string]string{
+		"Root":      systemdEscape(run.root),
+		"ExecStart": strings.Join(argv, " "),
+	}); err != nil {
+		return nil, nil, err
+	}
+
+	if err := systemdTimer.Execute(&timer, map[string]any{
+		"Period":   run.period,
+		"Calendar": run.period.name,
+		"Seconds":  int64(run.period.every.Seconds()),
+	}); err != nil {
+		return nil, nil, err
+	}
+
+	for _, unit := range []*bytes.Buffer{&service, &timer} {
+		if err := validateSystemdUnit(unit.String()); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return service.Bytes(), timer.Bytes(), nil
+}
+
+// validateSystemdUnit checks that every line of unit is blank, a [Section]
+// header or a Key=Value assignment inside a section.
+func validateSystemdUnit(unit string) error {
+	var inSection bool
+	for i, line := range strings.Split(unit, "\n") {
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			inSection = true
+		case !inSection:
+			return fmt.Errorf("invalid systemd unit, line %d is outside a section: %q", i+1, line)
+		default:
+			key, _, ok := strings.Cut(line, "=")
+			if !ok || key == "" || strings.ContainsAny(key, " \t") {
+				return fmt.Errorf("invalid systemd unit, line %d isn't Key=Value: %q", i+1, line)
+			}
+		}
+	}
+
+	return nil
+}
+
+// systemdEscape escapes the specifiers systemd expands in unit values.
+func systemdEscape(s string) string {
+	return strings.ReplaceAll(s, "%", "%%")
+}
+
+// systemdQuote quotes an ExecStart argument, escaping what systemd would
+// otherwise interpret: quotes, backslashes, specifiers and variables.
+func systemdQuote(arg string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "%", "%%", "$", "$$")
+	return `"` + r.Replace(arg) + `"`
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderSystemdUnits(t *testing.T) {
+	tests := []struct {
+		name        string
+		run         scheduledRun
+		wantService []string
+		wantTimer   []string
+		wantErr     bool
+	}{
+		{
+			name: "daily",
+			run: scheduledRun{
+				argv:   []string{"/usr/local/bin/git-gc", "--root", "/home/me/src", "--yes"},
+				root:   "/home/me/src",
+				period: period{name: "daily", every: 24 * time.Hour},
+			},
+			wantService: []string{
+				"Description=Run git gc in every repository under /home/me/src\n",
+				`ExecStart="/usr/local/bin/git-gc" "--root" "/home/me/src" "--yes"` + "\n",
+			},
+			wantTimer: []string{"Description=Run git-gc daily\n", "OnCalendar=daily\nPersistent=true\n"},
+		},
+		{
+			name: "interval",
+			run: scheduledRun{
+				argv:   []string{"/usr/local/bin/git-gc"},
+				root:   "/src",
+				period: period{every: 6 * time.Hour},
+			},
+			wantTimer: []string{"OnBootSec=15min\nOnUnitActiveSec=21600s\n"},
+		},
+		{
+			name: "quoting",
+			run: scheduledRun{
+				argv:   []string{"/opt/git gc/git-gc", "--root", `/home/me/"100%" $HOME\src`},
+				root:   `/home/me/"100%" $HOME\src`,
+				period: period{name: "weekly", every: 7 * 24 * time.Hour},
+			},
+			wantService: []string{
+				"Description=Run git gc in every repository under /home/me/\"100%%\" $HOME\\src\n",
+				`ExecStart="/opt/git gc/git-gc" "--root" "/home/me/\"100%%\" $$HOME\\src"` + "\n",
+			},
+		},
+		{
+			name: "newline",
+			run: scheduledRun{
+				argv:   []string{"/usr/local/bin/git-gc"},
+				root:   "/src\nrm -rf",
+				period: period{name: "daily", every: 24 * time.Hour},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, timer, err := renderSystemdUnits(tt.run)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("renderSystemdUnits() error = %v, want error %t", err, tt.wantErr)
+			}
+
+			for _, want := range tt.wantService {
+				if !strings.Contains(string(service), want) {
+					t.Errorf("service unit\n%s\nlacks %q", service, want)
+				}
+			}
+
+			for _, want := range tt.wantTimer {
+				if !strings.Contains(string(timer), want) {
+					t.Errorf("timer unit\n%s\nlacks %q", timer, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSystemdQuote(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{in: "--yes", want: `"--yes"`},
+		{in: "", want: `""`},
+		{in: "/home/me/my repos", want: `"/home/me/my repos"`},
+		{in: `say "hi"`, want: `"say \"hi\""`},
+		{in: `C:\src\`, want: `"C:\\src\\"`},
+		{in: "100%", want: `"100%%"`},
+		{in: "%h/src", want: `"%%h/src"`},
+		{in: "$HOME/src", want: `"$$HOME/src"`},
+		{in: "it's", want: `"it's"`},
+	}
+
+	for _, tt := range tests {
+		if got := systemdQuote(tt.in); got != tt.want {
+			t.Errorf("systemdQuote(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestValidateSystemdUnit(t *testing.T) {
+	tests := []struct {
+		name    string
+		unit    string
+		wantErr bool
+	}{
+		{name: "valid", unit: "[Unit]\nDescription=x\n\n[Service]\nExecStart=\"a\" \"b=c\"\n"},
+		{name: "outside a section", unit: "Description=x\n[Unit]\n", wantErr: true},
+		{name: "not an assignment", unit: "[Unit]\nDescription=x\nrm -rf /\n", wantErr: true},
+		{name: "empty key", unit: "[Unit]\n=x\n", wantErr: true},
+		{name: "space in key", unit: "[Unit]\nExec Start=x\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		if err := validateSystemdUnit(tt.unit); (err != nil) != tt.wantErr {
+			t.Errorf("%s: validateSystemdUnit() error = %v, want error %t", tt.name, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !linux && !darwin && !windows
+
+package main
+
+import "errors"
+
+// installSchedule sets up the platform's scheduler to start run, which isn't
+// supported here.
+func installSchedule(scheduledRun) (string, error) {
+	return "", errors.New("scheduling is not supported on this platform")
+}
+
+func uninstallSchedule() (string, error) {
+	return "", errors.New("scheduling is not supported on this platform")
+}
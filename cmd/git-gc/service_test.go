@@ -0,0 +1,75 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParsePeriod(t *testing.T) {
+	valid := map[string]period{
+		"hourly":  {name: "hourly", every: time.Hour},
+		"daily":   {name: "daily", every: 24 * time.Hour},
+		"weekly":  {name: "weekly", every: 7 * 24 * time.Hour},
+		"monthly": {name: "monthly", every: 30 * 24 * time.Hour},
+		"6h":      {every: 6 * time.Hour},
+		"3d":      {every: 3 * 24 * time.Hour},
+		"1m":      {every: time.Minute},
+	}
+	for in, want := range valid {
+		if got, err := parsePeriod(in); err != nil || got != want {
+			t.Errorf("parsePeriod(%q) = %+v, %v, want %+v", in, got, err, want)
+		}
+	}
+
+	// Runs more often than every minute would overlap
+	for _, in := range []string{"30s", "yearly", ""} {
+		if got, err := parsePeriod(in); err == nil {
+			t.Errorf("parsePeriod(%q) = %+v, want an error", in, got)
+		}
+	}
+}
+
+func TestXMLEscape(t *testing.T) {
+	for in, want := range map[string]string{
+		"/home/me/src": "/home/me/src",
+		"R&D <old>":    "R&amp;D &lt;old&gt;",
+		`say "it's"`:   "say &#34;it&#39;s&#34;",
+	} {
+		got, err := xmlEscape(in)
+		if err != nil || got != want {
+			t.Errorf("xmlEscape(%q) = %q, %v, want %q", in, got, err, want)
+		}
+
+		if err := checkXML([]byte("<a>" + got + "</a>")); err != nil {
+			t.Errorf("xmlEscape(%q) = %q, which isn't valid XML: %v", in, got, err)
+		}
+	}
+}
+
+func TestAbsFlagValue(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name, value, want string
+	}{
+		{"config", "git-gc.toml", filepath.Join(wd, "git-gc.toml")},
+		{"report", filepath.Join("out", "report.json"), filepath.Join(wd, "out", "report.json")},
+		{"backup-dir", filepath.Join(wd, "backups"), filepath.Join(wd, "backups")},
+		{"git", "git", "git"},
+		{"git", filepath.Join("bin", "git"), filepath.Join(wd, "bin", "git")},
+		{"parallel-device", "data=2", filepath.Join(wd, "data") + "=2"},
+		{"tasks", "fetch,gc", "fetch,gc"},
+		{"report", "", ""},
+	}
+
+	for _, tt := range tests {
+		if got, err := absFlagValue(tt.name, tt.value); err != nil || got != tt.want {
+			t.Errorf("absFlagValue(%q, %q) = %q, %v, want %q", tt.name, tt.value, got, err, tt.want)
+		}
+	}
+}
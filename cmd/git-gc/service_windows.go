@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"text/template"
+	"time"
+	"unicode/utf16"
+)
+
+// scheduledTask is the name of the Task Scheduler task running git-gc.
+const scheduledTask = "git-gc"
+
+// The task has no logon or idle trigger, which would start a run at every
+// logon or whenever the machine goes idle regardless of the period. Missed
+// runs start as soon as they can, e.g. at the next logon, and with
+// --when-idle runs wait for the machine to be idle instead.
+//
+// The declaration is added once the task is checked, since it says UTF-16
+var scheduledTaskXML = template.Must(template.New("task").Funcs(template.FuncMap{"xml": xmlEscape}).Parse(`<Task version="1.2" xmlns="http://schemas.microsoft.com/windows/2004/02/mit/task">
+  <RegistrationInfo>
+    <Description>Run git gc in every repository under {{xml .Root}}</Description>
+  </RegistrationInfo>
+  <Triggers>
+    {{- if eq .Period "hourly"}}
+    <TimeTrigger>
+      <StartBoundary>{{.Start}}</StartBoundary>
+      <Repetition>
+        <Interval>PT1H</Interval>
+      </Repetition>
+    </TimeTrigger>
+    {{- else if eq .Period "weekly"}}
+    <CalendarTrigger>
+      <StartBoundary>{{.Start}}</StartBoundary>
+      <ScheduleByWeek>
+        <DaysOfWeek>
+          <Monday />
+        </DaysOfWeek>
+        <WeeksInterval>1</WeeksInterval>
+      </ScheduleByWeek>
+    </CalendarTrigger>
+    {{- else if eq .Period "monthly"}}
+    <CalendarTrigger>
+      <StartBoundary>{{.Start}}</StartBoundary>
+      <ScheduleByMonth>
+        <DaysOfMonth>
+          <Day>1</Day>
+        </DaysOfMonth>
+        <Months>
+          <January /><February /><March /><April /><May /><June /><July /><August /><September /><October /><November /><December />
+        </Months>
+      </ScheduleByMonth>
+    </CalendarTrigger>
+    {{- else if .Days}}
+    <CalendarTrigger>
+      <StartBoundary>{{.Start}}</StartBoundary>
+      <ScheduleByDay>
+        <DaysInterval>{{.Days}}</DaysInterval>
+      </ScheduleByDay>
+    </CalendarTrigger>
+    {{- else}}
+    <TimeTrigger>
+      <StartBoundary>{{.Start}}</StartBoundary>
+      <Repetition>
+        <Interval>PT{{.Minutes}}M</Interval>
+      </Repetition>
+    </TimeTrigger>
+    {{- end}}
+  </Triggers>
+  <Settings>
+    <MultipleInstancesPolicy>IgnoreNew</MultipleInstancesPolicy>
+    <DisallowStartIfOnBatteries>false</DisallowStartIfOnBatteries>
+    <StopIfGoingOnBatteries>false</StopIfGoingOnBatteries>
+    <StartWhenAvailable>true</StartWhenAvailable>
+    {{- if .WhenIdle}}
+    <IdleSettings>
+      <Duration>PT5M</Duration>
+      <WaitTimeout>PT12H</WaitTimeout>
+      <StopOnIdleEnd>false</StopOnIdleEnd>
+      <RestartOnIdle>false</RestartOnIdle>
+    </IdleSettings>
+    {{- end}}
+    <ExecutionTimeLimit>PT0S</ExecutionTimeLimit>
+    <Priority>7</Priority>
+    {{- if .WhenIdle}}
+    <RunOnlyIfIdle>true</RunOnlyIfIdle>
+    {{- end}}
+  </Settings>
+  <Actions>
+    <Exec>
+      <Command>cmd.exe</Command>
+      <Arguments>{{xml .Arguments}}</Arguments>
+    </Exec>
+  </Actions>
+</Task>
+`))
+
+// installSchedule registers a Task Scheduler task starting run.
+func installSchedule(run scheduledRun) (string, error) {
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("could not determine the log directory: %w", err)
+	}
+
+	logPath := filepath.Join(cache, "git-gc", "git-gc.log")
+	if err := os.MkdirAll(filepath.Dir(logPath), 0o755); err != nil {
+		return "", fmt.Errorf("could not create %s: %w", filepath.Dir(logPath), err)
+	}
+
+	task, err := renderScheduledTask(run, logPath, time.Now())
+	if err != nil {
+		return "", err
+	}
+
+	f, err := os.CreateTemp("", "git-gc-task-*.xml")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.Write(task)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("could not write the task: %w", err)
+	}
+
+	// /F replaces the task of an earlier install
+	if err := schtasks("/Create", "/TN", scheduledTask, "/XML", f.Name(), "/F"); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("Registered the %s task, running %s and logging to %s; see Task Scheduler", scheduledTask, run.period, logPath), nil
+}
+
+// uninstallSchedule deletes the Task Scheduler task.
+func uninstallSchedule() (string, error) {
+	if schtasks("/Query", "/TN", scheduledTask) != nil {
+		return "", errors.New("no schedule is installed")
+	}
+
+	if err := schtasks("/Delete", "/TN", scheduledTask, "/F"); err != nil {
+		return "", err
+	}
+
+	return "Deleted the git-gc task", nil
+}
+
+func schtasks(args ...string) error {
+	out, err := exec.Command("schtasks", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("schtasks %s: %w: %s", args[0], err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
+
+// renderScheduledTask renders the task definition for run, starting from
+// now, and checks that it came out as well-formed XML. It's returned as
+// UTF-16, which is what schtasks reads reliably.
+func renderScheduledTask(run scheduledRun, logPath string, now time.Time) ([]byte, error) {
+	every := run.period.every
+	days, minutes := 0, int64(every.Minutes())
+	if every%(24*time.Hour) == 0 {
+		days = int(every / (24 * time.Hour))
+	} else if every > 31*24*time.Hour {
+		return nil, fmt.Errorf("can't schedule runs every %s, use whole days for intervals over 31 days", every)
+	}
+
+	// Named periods start at midnight, like systemd's and launchd's
+	start := now
+	if run.period.name != "" {
+		start = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	}
+
+	// Tasks have no console to log to, so cmd redirects the output
+	argv := make([]string, len(run.argv))
+	for i, arg := range run.argv {
+		argv[i] = cmdQuote(arg)
+	}
+
+	arguments := fmt.Sprintf(`/S /C "%s >> %s 2>&1"`, strings.Join(argv, " "), cmdQuote(logPath))
+
+	var task bytes.Buffer
+	if err := scheduledTaskXML.Execute(&task, map[string]any{
+		"Root":      run.root,
+		"Period":    run.period.name,
+		"Start":     start.Format("2006-01-02T15:04:05"),
+		"Days":      days,
+		"Minutes":   minutes,
+		"Arguments": arguments,
+		"WhenIdle":  run.whenIdle,
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := checkXML(task.Bytes()); err != nil {
+		return nil, fmt.Errorf("invalid scheduled task: %w", err)
+	}
+
+	// Little endian with a byte order mark
+	doc := utf16.Encode([]rune(`<?xml version="1.0" encoding="UTF-16"?>` + "\r\n" + task.String()))
+	out := make([]byte, 2, 2+2*len(doc))
+	binary.LittleEndian.PutUint16(out, 0xfeff)
+	for _, c := range doc {
+		out = binary.LittleEndian.AppendUint16(out, c)
+	}
+
+	return out, nil
+}
+
+// cmdQuote quotes arg so that cmd leaves characters such as & alone, and the
+// program gets it back as is.
+func cmdQuote(arg string) string {
+	if quoted := syscall.EscapeArg(arg); strings.HasPrefix(quoted, `"`) {
+		return quoted
+	}
+
+	// Backslashes are only special before a quote, like the closing one
+	return `"` + arg + strings.Repeat(`\`, len(arg)-len(strings.TrimRight(arg, `\`))) + `"`
+}
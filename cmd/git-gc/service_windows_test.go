@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf16"
+)
+
+func TestRenderScheduledTask(t *testing.T) {
+	now := time.Date(2024, 3, 5, 14, 30, 0, 0, time.Local)
+	tests := []struct {
+		name    string
+		run     scheduledRun
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "daily",
+			run: scheduledRun{
+				argv:   []string{`C:\Program Files\git-gc\git-gc.exe`, "--root", `C:\src`},
+				root:   `C:\src`,
+				period: period{name: "daily", every: 24 * time.Hour},
+			},
+			want: []string{
+				"<Description>Run git gc in every repository under C:\\src</Description>",
+				"<StartBoundary>2024-03-05T00:00:00</StartBoundary>\n      <ScheduleByDay>\n        <DaysInterval>1</DaysInterval>",
+				`<Arguments>/S /C &#34;&#34;C:\Program Files\git-gc\git-gc.exe&#34; &#34;--root&#34; &#34;C:\src&#34; &gt;&gt; &#34;C:\log.txt&#34; 2&gt;&amp;1&#34;</Arguments>`,
+			},
+		},
+		{
+			name: "hourly",
+			run: scheduledRun{
+				argv:   []string{`C:\git-gc.exe`},
+				period: period{name: "hourly", every: time.Hour},
+			},
+			want: []string{"<Interval>PT1H</Interval>"},
+		},
+		{
+			name: "interval",
+			run: scheduledRun{
+				argv:   []string{`C:\git-gc.exe`},
+				period: period{every: 90 * time.Minute},
+			},
+			want: []string{"<StartBoundary>2024-03-05T14:30:00</StartBoundary>", "<Interval>PT90M</Interval>"},
+		},
+		{
+			name: "when idle",
+			run: scheduledRun{
+				argv:     []string{`C:\git-gc.exe`, "--when-idle"},
+				period:   period{name: "weekly", every: 7 * 24 * time.Hour},
+				whenIdle: true,
+			},
+			want: []string{"<Monday />", "<IdleSettings>", "<RunOnlyIfIdle>true</RunOnlyIfIdle>"},
+		},
+		{
+			name: "escaping",
+			run: scheduledRun{
+				argv:   []string{`C:\R&D\git-gc.exe`, "--root", `C:\my "src"\`},
+				root:   `C:\R&D <src>`,
+				period: period{name: "daily", every: 24 * time.Hour},
+			},
+			want: []string{
+				"under C:\\R&amp;D &lt;src&gt;</Description>",
+				`&#34;C:\R&amp;D\git-gc.exe&#34; &#34;--root&#34; &#34;C:\my \&#34;src\&#34;\\&#34;`,
+			},
+		},
+		{
+			name: "too long",
+			run: scheduledRun{
+				argv:   []string{`C:\git-gc.exe`},
+				period: period{every: 40*24*time.Hour + time.Hour},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := renderScheduledTask(tt.run, `C:\log.txt`, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("renderScheduledTask() error = %v, want error %t", err, tt.wantErr)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if len(out) < 2 || len(out)%2 != 0 || binary.LittleEndian.Uint16(out) != 0xfeff {
+				t.Fatalf("the task isn't UTF-16LE with a byte order mark: % x", out[:min(len(out), 8)])
+			}
+
+			units := make([]uint16, len(out)/2-1)
+			for i := range units {
+				units[i] = binary.LittleEndian.Uint16(out[2+2*i:])
+			}
+
+			task := string(utf16.Decode(units))
+			if !strings.HasPrefix(task, `<?xml version="1.0" encoding="UTF-16"?>`) {
+				t.Errorf("task starts with %q, want the UTF-16 XML declaration", task[:min(len(task), 40)])
+			}
+
+			for _, want := range tt.want {
+				if !strings.Contains(task, want) {
+					t.Errorf("task\n%s\nlacks %q", task, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCmdQuote(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{in: `C:\git-gc.exe`, want: `"C:\git-gc.exe"`},
+		{in: "--yes", want: `"--yes"`},
+		{in: "", want: `""`},
+		{in: `C:\Program Files\git-gc.exe`, want: `"C:\Program Files\git-gc.exe"`},
+		{in: `C:\src\`, want: `"C:\src\\"`},
+		{in: `C:\my src\`, want: `"C:\my src\\"`},
+		{in: `say "hi"`, want: `"say \"hi\""`},
+	}
+
+	for _, tt := range tests {
+		if got := cmdQuote(tt.in); got != tt.want {
+			t.Errorf("cmdQuote(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// shard selects a deterministic part of the repos, so the work can be
+// spread over several nights or machines.
+type shard struct {
+	index int // 1-based
+	count int // 0 to select every repo
+}
+
+// parseShard parses "i/n", e.g. "3/7" for the third of seven shards.
+func parseShard(s string) (shard, error) {
+	if s == "" {
+		return shard{}, nil
+	}
+
+	i, n, ok := strings.Cut(s, "/")
+	index, err1 := strconv.Atoi(strings.TrimSpace(i))
+	count, err2 := strconv.Atoi(strings.TrimSpace(n))
+	if !ok || err1 != nil || err2 != nil || count < 1 || index < 1 || index > count {
+		return shard{}, fmt.Errorf("invalid shard %q, expected i/n with 1 <= i <= n", s)
+	}
+
+	return shard{index: index, count: count}, nil
+}
+
+// filter returns the dirs in the shard. Repos are assigned by a hash of their
+// path relative to root, so every repo keeps its shard as others come and go,
+// and machines with the same tree under different roots agree.
+func (s shard) filter(root string, dirs []string) []string {
+	if s.count == 0 {
+		return dirs
+	}
+
+	return slices.DeleteFunc(dirs, func(dir string) bool {
+		rel, err := filepath.Rel(root, dir)
+		if err != nil {
+			rel = dir
+		}
+
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(filepath.ToSlash(rel)))
+		return int(h.Sum32()%uint32(s.count)) != s.index-1
+	})
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestParseShard(t *testing.T) {
+	valid := map[string]shard{
+		"":        {},
+		"1/1":     {index: 1, count: 1},
+		"3/7":     {index: 3, count: 7},
+		" 2 / 4 ": {index: 2, count: 4},
+	}
+	for in, want := range valid {
+		if got, err := parseShard(in); err != nil || got != want {
+			t.Errorf("parseShard(%q) = %+v, %v, want %+v", in, got, err, want)
+		}
+	}
+
+	for _, in := range []string{"0/3", "4/3", "-1/3", "1/0", "3", "a/b", "1/2/3"} {
+		if got, err := parseShard(in); err == nil {
+			t.Errorf("parseShard(%q) = %+v, want an error", in, got)
+		}
+	}
+}
+
+// TestShardFilter checks that the shards split the repos between them, the
+// same way whatever the root.
+func TestShardFilter(t *testing.T) {
+	var rels []string
+	for _, name := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "x/y", "x/z"} {
+		rels = append(rels, filepath.FromSlash(name))
+	}
+
+	under := func(root string) []string {
+		dirs := make([]string, len(rels))
+		for i, rel := range rels {
+			dirs[i] = filepath.Join(root, rel)
+		}
+
+		return dirs
+	}
+
+	const count = 3
+	seen := make(map[string]int)
+	for index := 1; index <= count; index++ {
+		s := shard{index: index, count: count}
+		here := s.filter("/home/me/src", under("/home/me/src"))
+		there := s.filter("/srv/src", under("/srv/src"))
+		if len(here) != len(there) {
+			t.Fatalf("shard %d/%d has %d repos under one root and %d under another", index, count, len(here), len(there))
+		}
+
+		for i, dir := range here {
+			rel, _ := filepath.Rel("/home/me/src", dir)
+			if other, _ := filepath.Rel("/srv/src", there[i]); other != rel {
+				t.Errorf("shard %d/%d has %s under one root and %s under another", index, count, rel, other)
+			}
+
+			seen[rel]++
+		}
+	}
+
+	for _, rel := range rels {
+		if seen[rel] != 1 {
+			t.Errorf("%s is in %d shards, want 1", rel, seen[rel])
+		}
+	}
+
+	if got := (shard{}).filter("/src", under("/src")); !slices.Equal(got, under("/src")) {
+		t.Errorf("the zero shard selected %v, want every repo", got)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// parseSize parses a byte size such as "512m", "2g", "1.5GiB" or "4096".
+// Suffixes are binary multiples, matching how git reads sizes in its config.
+func parseSize(s string) (int64, error) {
+	num := strings.TrimSpace(s)
+	lower := strings.ToLower(num)
+	lower = strings.TrimSuffix(strings.TrimSuffix(lower, "ib"), "b")
+
+	mult := int64(1)
+	if n := len(lower); n > 0 {
+		switch lower[n-1] {
+		case 'k':
+			mult = 1 << 10
+		case 'm':
+			mult = 1 << 20
+		case 'g':
+			mult = 1 << 30
+		case 't':
+			mult = 1 << 40
+		}
+
+		if mult > 1 {
+			lower = lower[:n-1]
+		}
+	}
+
+	v, err := strconv.ParseFloat(lower, 64)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+
+	return int64(v * float64(mult)), nil
+}
+
+// gitDirSize returns the total size of the files in dir's git dir, 0 if it
+// can't be measured.
+func gitDirSize(ctx context.Context, dir string) int64 {
+	gitDir, err := absoluteGitDir(ctx, dir)
+	if err != nil {
+		return 0
+	}
+
+	var size int64
+	_ = filepath.WalkDir(gitDir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return nil
+		}
+
+		if fi, err := d.Info(); err == nil {
+			size += fi.Size()
+		}
+
+		return nil
+	})
+
+	return size
+}
+
+// formatSize formats a byte size with the largest binary unit it has at least
+// one of, e.g. "1.5 GiB".
+func formatSize(n int64) string {
+	const units = "KMGTPE"
+	if n < 1<<10 && n > -1<<10 {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	v, i := float64(n)/(1<<10), 0
+	for ; (v >= 1<<10 || v <= -1<<10) && i < len(units)-1; i++ {
+		v /= 1 << 10
+	}
+
+	return fmt.Sprintf("%.1f %ciB", v, units[i])
+}
@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+func TestParseSize(t *testing.T) {
+	valid := map[string]int64{
+		"4096":   4096,
+		"0":      0,
+		"1b":     1,
+		"1k":     1 << 10,
+		"1kb":    1 << 10,
+		" 2K ":   2 << 10,
+		"512m":   512 << 20,
+		"2g":     2 << 30,
+		"1.5GiB": 3 << 29,
+		"1t":     1 << 40,
+	}
+	for in, want := range valid {
+		if got, err := parseSize(in); err != nil || got != want {
+			t.Errorf("parseSize(%q) = %d, %v, want %d", in, got, err, want)
+		}
+	}
+
+	for _, in := range []string{"", "m", "-1", "-1g", "10x", "1 gb"} {
+		if got, err := parseSize(in); err == nil {
+			t.Errorf("parseSize(%q) = %d, want an error", in, got)
+		}
+	}
+}
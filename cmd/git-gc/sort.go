@@ -0,0 +1,94 @@
+package main
+
+import (
+	"cmp"
+	"slices"
+)
+
+// historyOrder is the order the history lists finished repos in.
+type historyOrder int
+
+const (
+	orderFinished historyOrder = iota // as they finished
+	orderTook                         // slowest first
+	orderFreed                        // most space freed first
+	orderStatus                       // failed, then skipped, then succeeded
+
+	numHistoryOrders
+)
+
+func (o historyOrder) String() string {
+	switch o {
+	case orderTook:
+		return "duration"
+	case orderFreed:
+		return "space freed"
+	case orderStatus:
+		return "status"
+	default:
+		return "finish time"
+	}
+}
+
+// repoResult is how a finished repo ended, for sorting the history and the
+// report. The sizes of its git dir are 0 when they weren't measured.
+type repoResult struct {
+	status        repoStatus
+	freed         int64
+	before, after int64
+}
+
+// sortedHistory returns the lines of the history in m.order. The lines
+// printed for a repo at once stay together, and lines about no repo go
+// last.
+func (m model) sortedHistory() []historyLine {
+	if m.order == orderFinished {
+		return m.printed
+	}
+
+	// Consecutive lines about the same repo were printed at once
+	var entries [][]historyLine
+	for i, line := range m.printed {
+		if i > 0 && line.dir != "" && line.dir == m.printed[i-1].dir {
+			entries[len(entries)-1] = append(entries[len(entries)-1], line)
+			continue
+		}
+
+		entries = append(entries, []historyLine{line})
+	}
+
+	rank := func(status repoStatus) int {
+		switch status {
+		case statusFailed:
+			return 0
+		case statusSkipped:
+			return 1
+		}
+
+		return 2
+	}
+
+	slices.SortStableFunc(entries, func(a, b []historyLine) int {
+		x, xok := m.results[a[0].dir]
+		y, yok := m.results[b[0].dir]
+		switch {
+		case xok && !yok:
+			return -1
+		case !xok && yok:
+			return 1
+		case !xok:
+			return 0
+		}
+
+		switch m.order {
+		case orderTook:
+			return cmp.Compare(m.took[b[0].dir], m.took[a[0].dir])
+		case orderFreed:
+			return cmp.Compare(y.freed, x.freed)
+		default:
+			return cmp.Compare(rank(x.status), rank(y.status))
+		}
+	})
+
+	return slices.Concat(entries...)
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// stateStore persists per-repo bookkeeping between runs. It's safe for
+// concurrent use since tasks consult it from their own goroutines.
+type stateStore struct {
+	mu   sync.Mutex
+	path string
+	data state
+}
+
+type state struct {
+	Repos map[string]*repoState `json:"repos"`
+
+	// Remaining lists the repos a run left for the next one because it ran
+	// out of budget, by root.
+	Remaining map[string][]string `json:"remaining,omitempty"`
+
+	// Runs tracks the run in progress, or the last one that didn't finish,
+	// by root.
+	Runs map[string]*runState `json:"runs,omitempty"`
+}
+
+type repoState struct {
+	LastAggressive time.Time `json:"last_aggressive"`
+
+	// Fingerprints are what the repo looked like after the last successful
+	// run of each set of tasks, by their comma separated names.
+	Fingerprints map[string]string `json:"fingerprints,omitempty"`
+
+	// Took is how long the repo's tasks took the last time they all
+	// succeeded, for estimating how long a run takes.
+	Took time.Duration `json:"took,omitempty"`
+}
+
+// defaultStatePath returns where state is kept, e.g. ~/.cache/git-gc/state.json.
+func defaultStatePath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(dir, "git-gc", "state.json")
+}
+
+// loadState reads the state file at path. A missing file is an empty state,
+// and an empty path keeps state in memory only.
+func loadState(path string) (*stateStore, error) {
+	data, err := readState(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &stateStore{path: path, data: data}, nil
+}
+
+// readState reads the state file at path, which may be missing or "".
+func readState(path string) (state, error) {
+	data := state{Repos: make(map[string]*repoState)}
+	if path == "" {
+		return data, nil
+	}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return data, nil
+	}
+
+	if err != nil {
+		return state{}, fmt.Errorf("could not read state file: %w", err)
+	}
+
+	if err := json.Unmarshal(b, &data); err != nil {
+		return state{}, fmt.Errorf("could not parse state file %s: %w", path, err)
+	}
+
+	if data.Repos == nil {
+		data.Repos = make(map[string]*repoState)
+	}
+
+	return data, nil
+}
+
+func (s *stateStore) lastAggressive(dir string) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if rs, ok := s.data.Repos[dir]; ok {
+		return rs.LastAggressive
+	}
+
+	return time.Time{}
+}
+
+func (s *stateStore) recordAggressive(dir string, at time.Time) error {
+	return s.update(func(data *state) bool {
+		data.repo(dir).LastAggressive = at
+		return true
+	})
+}
+
+func (s *stateStore) fingerprint(dir, tasks string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if rs, ok := s.data.Repos[dir]; ok {
+		return rs.Fingerprints[tasks]
+	}
+
+	return ""
+}
+
+func (s *stateStore) recordFingerprint(dir, tasks, fp string) error {
+	return s.update(func(data *state) bool {
+		rs := data.repo(dir)
+		if rs.Fingerprints == nil {
+			rs.Fingerprints = make(map[string]string)
+		}
+
+		rs.Fingerprints[tasks] = fp
+		return true
+	})
+}
+
+// durations returns how long each of dirs took the last time, for the
+// ones that ran before.
+func (s *stateStore) durations(dirs []string) map[string]time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	took := make(map[string]time.Duration)
+	for _, dir := range dirs {
+		if rs, ok := s.data.Repos[dir]; ok && rs.Took > 0 {
+			took[dir] = rs.Took
+		}
+	}
+
+	return took
+}
+
+// remaining returns the repos under root the last run left over.
+func (s *stateStore) remaining(root string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.data.Remaining[root]
+}
+
+// setRemaining records the repos under root left for the next run.
+func (s *stateStore) setRemaining(root string, dirs []string) error {
+	return s.update(func(data *state) bool {
+		if len(dirs) == 0 && len(data.Remaining[root]) == 0 {
+			return false
+		}
+
+		if data.Remaining == nil {
+			data.Remaining = make(map[string][]string)
+		}
+
+		if len(dirs) == 0 {
+			delete(data.Remaining, root)
+		} else {
+			data.Remaining[root] = dirs
+		}
+
+		return true
+	})
+}
+
+// lastRun returns the unfinished run in root, or nil if there's none.
+func (s *stateStore) lastRun(root string) *runState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if run, ok := s.data.Runs[root]; ok {
+		return &runState{Started: run.Started, Finished: slices.Clone(run.Finished)}
+	}
+
+	return nil
+}
+
+// startRun records that a run started in root, forgetting the repos an
+// earlier one finished unless it's resumed.
+func (s *stateStore) startRun(root string, resume bool) error {
+	return s.update(func(data *state) bool {
+		if data.Runs == nil {
+			data.Runs = make(map[string]*runState)
+		}
+
+		if run, ok := data.Runs[root]; ok && resume {
+			run.Started = time.Now()
+		} else {
+			data.Runs[root] = &runState{Started: time.Now()}
+		}
+
+		return true
+	})
+}
+
+// recordProgress records that the run in root is done with the finished
+// repos, and how long the ones in took took.
+func (s *stateStore) recordProgress(root string, finished []string, took map[string]time.Duration) error {
+	return s.update(func(data *state) bool {
+		changed := len(took) > 0
+		for dir, d := range took {
+			data.repo(dir).Took = d
+		}
+
+		if run, ok := data.Runs[root]; ok {
+			for _, dir := range finished {
+				if !slices.Contains(run.Finished, dir) {
+					run.Finished = append(run.Finished, dir)
+					changed = true
+				}
+			}
+		}
+
+		return changed
+	})
+}
+
+// endRun forgets the run in root once it's complete.
+func (s *stateStore) endRun(root string) error {
+	return s.update(func(data *state) bool {
+		if _, ok := data.Runs[root]; !ok {
+			return false
+		}
+
+		delete(data.Runs, root)
+		return true
+	})
+}
+
+// repo returns the state for dir, creating it if needed.
+func (data *state) repo(dir string) *repoState {
+	rs, ok := data.Repos[dir]
+	if !ok {
+		rs = &repoState{}
+		data.Repos[dir] = rs
+	}
+
+	return rs
+}
+
+// stateLockWait is how long saving waits for another git-gc saving the same
+// state file.
+const stateLockWait = 10 * time.Second
+
+// update applies change, which reports whether it changed anything, and
+// saves the result. Other git-gc processes share the state file, e.g. runs
+// in other roots, so the change is applied to what's in the file right
+// now, under a lock, rather than to what this process read earlier.
+func (s *stateStore) update(change func(*state) bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.path == "" {
+		change(&s.data)
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("could not create state dir: %w", err)
+	}
+
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	data, err := readState(s.path)
+	if err != nil {
+		return err
+	}
+
+	s.data = data
+	if !change(&s.data) {
+		return nil
+	}
+
+	return s.save()
+}
+
+// lock takes the lock of the state file, waiting up to stateLockWait for
+// another process to release it.
+func (s *stateStore) lock() (func(), error) {
+	deadline := time.Now().Add(stateLockWait)
+	for {
+		release, owner, err := takePIDLock(s.path + ".lock")
+		if err != nil || release != nil {
+			return release, err
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("state file is locked by pid %d on %s", owner.pid, owner.host)
+		}
+
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// save writes the state file atomically, through a temporary file of its
+// own in the same directory. The state file lock must be held.
+func (s *stateStore) save() error {
+	b, err := json.MarshalIndent(s.data, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("could not write state file: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.Write(b)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	if err != nil {
+		return fmt.Errorf("could not write state file: %w", err)
+	}
+
+	return os.Rename(f.Name(), s.path)
+}
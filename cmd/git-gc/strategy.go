@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// strategy is how the gc task collects garbage in a repo.
+type strategy string
+
+const (
+	strategyNormal     strategy = "normal"     // git gc
+	strategyAuto       strategy = "auto"       // git gc --auto
+	strategyAggressive strategy = "aggressive" // git gc --aggressive
+	strategyAdaptive   strategy = "adaptive"   // pick one of the above per repo
+)
+
+var strategies = []strategy{strategyNormal, strategyAuto, strategyAggressive, strategyAdaptive}
+
+// Thresholds used by the adaptive strategy.
+const (
+	tidyLooseObjects  = 1000
+	tidyPacks         = 5
+	fragmentedPacks   = 20
+	aggressiveMaxSize = 2 << 30 // aggressive gc on bigger packs takes too long
+	aggressiveMinAge  = 90 * 24 * time.Hour
+)
+
+func parseStrategy(s string) (strategy, error) {
+	for _, st := range strategies {
+		if string(st) == s {
+			return st, nil
+		}
+	}
+
+	return "", fmt.Errorf("unknown strategy %q (available: %v)", s, strategies)
+}
+
+// parseInterval parses a duration that may also be given in days or weeks,
+// e.g. "30d" or "2w", on top of what time.ParseDuration accepts.
+func parseInterval(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			v, err := strconv.ParseFloat(n, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid interval %q", s)
+			}
+
+			return time.Duration(v * float64(unit)), nil
+		}
+	}
+
+	return time.ParseDuration(s)
+}
+
+// upgradeToAggressive reports whether a normal gc is due to be aggressive
+// because the last aggressive run is more than every ago, and explains why.
+func upgradeToAggressive(every time.Duration, lastAggressive, now time.Time) (bool, string) {
+	if every <= 0 {
+		return false, ""
+	}
+
+	if lastAggressive.IsZero() {
+		return true, "never aggressively collected"
+	}
+
+	if age := now.Sub(lastAggressive); age >= every {
+		return true, fmt.Sprintf("last aggressive gc %dd ago", int(age.Hours()/24))
+	}
+
+	return false, ""
+}
+
+func (s strategy) gcArgs() []string {
+	switch s {
+	case strategyAuto:
+		return []string{"gc", "--auto"}
+	case strategyAggressive:
+		return []string{"gc", "--aggressive"}
+	default:
+		return []string{"gc"}
+	}
+}
+
+// repoStats is the subset of `git count-objects -v` the adaptive strategy
+// looks at.
+type repoStats struct {
+	looseObjects int
+	packs        int
+	packSize     int64 // bytes
+	looseSize    int64 // bytes
+}
+
+func inspectRepo(ctx context.Context, dir string) (repoStats, error) {
+	out, err := gitOutput(ctx, dir, "count-objects", "-v")
+	if err != nil {
+		return repoStats{}, fmt.Errorf("could not count objects: %w", err)
+	}
+
+	var stats repoStats
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		key, value, ok := strings.Cut(sc.Text(), ": ")
+		if !ok {
+			continue
+		}
+
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		switch key {
+		case "count":
+			stats.looseObjects = int(n)
+		case "size":
+			stats.looseSize = n * 1024
+		case "packs":
+			stats.packs = int(n)
+		case "size-pack":
+			stats.packSize = n * 1024
+		}
+	}
+
+	return stats, nil
+}
+
+// promisorRemote returns the name of the promisor remote of a partial clone,
+// or "" if dir is a full clone.
+func promisorRemote(ctx context.Context, dir string) string {
+	out, err := gitOutput(ctx, dir, "config", "--get-regexp", `^remote\..*\.promisor$`)
+	if err == nil {
+		sc := bufio.NewScanner(bytes.NewReader(out))
+		for sc.Scan() {
+			key, value, _ := strings.Cut(sc.Text(), " ")
+			if value == "true" {
+				return strings.TrimSuffix(strings.TrimPrefix(key, "remote."), ".promisor")
+			}
+		}
+	}
+
+	// Older partial clones only record the remote in extensions.partialClone
+	out, err = gitOutput(ctx, dir, "config", "--get", "extensions.partialClone")
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(out))
+}
+
+// chooseStrategy picks how to gc a repo from its stats and when it was last
+// aggressively collected, and explains why.
+func chooseStrategy(stats repoStats, lastAggressive, now time.Time) (strategy, string) {
+	switch {
+	case stats.looseObjects < tidyLooseObjects && stats.packs <= tidyPacks:
+		return strategyAuto, fmt.Sprintf("tidy, %d loose objects in %d packs", stats.looseObjects, stats.packs)
+	case stats.packs > fragmentedPacks && stats.packSize <= aggressiveMaxSize:
+		if lastAggressive.IsZero() {
+			return strategyAggressive, fmt.Sprintf("%d packs, never aggressively collected", stats.packs)
+		}
+
+		if age := now.Sub(lastAggressive); age >= aggressiveMinAge {
+			return strategyAggressive, fmt.Sprintf(
+				"%d packs, last aggressive gc %dd ago", stats.packs, int(age.Hours()/24),
+			)
+		}
+	}
+
+	return strategyNormal, fmt.Sprintf("%d loose objects in %d packs", stats.looseObjects, stats.packs)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseInterval(t *testing.T) {
+	valid := map[string]time.Duration{
+		"90m":   90 * time.Minute,
+		"2h30m": 150 * time.Minute,
+		"1d":    24 * time.Hour,
+		"0.5d":  12 * time.Hour,
+		"2w":    14 * 24 * time.Hour,
+		"1.5w":  252 * time.Hour,
+	}
+	for in, want := range valid {
+		if got, err := parseInterval(in); err != nil || got != want {
+			t.Errorf("parseInterval(%q) = %s, %v, want %s", in, got, err, want)
+		}
+	}
+
+	for _, in := range []string{"", "d", "xw", "3", "weekly"} {
+		if got, err := parseInterval(in); err == nil {
+			t.Errorf("parseInterval(%q) = %s, want an error", in, got)
+		}
+	}
+}
+
+func TestUpgradeToAggressive(t *testing.T) {
+	now := time.Date(2024, 3, 5, 2, 0, 0, 0, time.UTC)
+	tests := []struct {
+		every          time.Duration
+		lastAggressive time.Time
+		want           bool
+	}{
+		{every: 0, lastAggressive: time.Time{}, want: false},
+		{every: 30 * 24 * time.Hour, lastAggressive: time.Time{}, want: true},
+		{every: 30 * 24 * time.Hour, lastAggressive: now.AddDate(0, 0, -29), want: false},
+		{every: 30 * 24 * time.Hour, lastAggressive: now.AddDate(0, 0, -30), want: true},
+	}
+
+	for _, tt := range tests {
+		if got, why := upgradeToAggressive(tt.every, tt.lastAggressive, now); got != tt.want || got == (why == "") {
+			t.Errorf("upgradeToAggressive(%s, %s) = %t, %q, want %t with a reason if true", tt.every, tt.lastAggressive, got, why, tt.want)
+		}
+	}
+}
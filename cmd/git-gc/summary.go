@@ -0,0 +1,121 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbletea"
+)
+
+// slowestRepos is how many of the slowest repos the summary lists.
+const slowestRepos = 5
+
+// summary renders the screen shown once the run is over: what happened to
+// the repos, how long they took, how much space was freed, and what failed.
+func (m model) summary() string {
+	var b strings.Builder
+	total := len(m.directories)
+	if m.quitting {
+		b.WriteString(tr("Stopped! Ran %s on %d of %d repos.", m.action(), m.index-len(m.skipped), total-len(m.skipped)) + "\n")
+		if m.failedFast {
+			b.WriteString(tr("Stopped at the first failure because of --fail-fast.") + "\n")
+		}
+	} else {
+		b.WriteString(tr("Done! Ran %s on %d repos.", m.action(), total-len(m.skipped)-len(m.remaining)) + "\n")
+	}
+	if len(m.remaining) > 0 {
+		b.WriteString(tr("Out of budget, left %d repos for the next run.", len(m.remaining)) + "\n")
+	}
+
+	failed := len(m.failures)
+	b.WriteString("\n" + tr("Processed %d repos: %d succeeded, %d failed, %d skipped.",
+		m.index, m.index-failed-len(m.skipped), failed, len(m.skipped)) + "\n")
+
+	if len(m.took) > 0 {
+		var sum time.Duration
+		for _, d := range m.took {
+			sum += d
+		}
+
+		elapsed := m.lastDone.Sub(m.startAt)
+		b.WriteString(tr("Took %s, %s per repo on average, %.1f repos per minute.",
+			formatTook(elapsed), formatTook(sum/time.Duration(len(m.took))), float64(m.index)/elapsed.Minutes()) + "\n")
+	}
+
+	switch {
+	case m.reclaim > 0:
+		b.WriteString(tr("Freed %s in total.", formatSize(m.reclaim)) + "\n")
+	case m.reclaim < 0:
+		b.WriteString(tr("Grew by %s in total.", formatSize(-m.reclaim)) + "\n")
+	}
+
+	if len(m.reran) > 0 {
+		recovered := len(slices.DeleteFunc(slices.Clone(m.reran), m.failed))
+		b.WriteString(tr("Ran %d failed repos again, %d succeeded the second time.", len(m.reran), recovered) + "\n")
+	}
+
+	// Only worth listing when there's something to compare them to
+	if len(m.took) > 1 {
+		slowest := slices.SortedFunc(maps.Keys(m.took), func(a, b string) int {
+			return cmp.Or(cmp.Compare(m.took[b], m.took[a]), strings.Compare(a, b))
+		})
+
+		b.WriteString("\n" + tr("Slowest repos:") + "\n")
+		for _, dir := range slowest[:min(slowestRepos, len(slowest))] {
+			fmt.Fprintf(&b, "%8s  %s\n", formatTook(m.took[dir]), dir)
+		}
+	}
+
+	if len(m.failures) > 0 {
+		b.WriteString("\n" + tr(m.failureLabel(), len(m.failures)) + "\n")
+		for _, f := range m.failures {
+			fmt.Fprintf(&b, "%s %s: %s: %v\n", m.styles.cross, f.dir, f.task, f.err)
+		}
+	}
+
+	return b.String()
+}
+
+// retryable reports whether the summary should offer to run the failed repos
+// again, which needs someone to answer.
+func (m model) retryable() bool {
+	return m.plain == nil && !m.unattended && !m.quitting && m.remaining == nil && len(m.failedDirs()) > 0
+}
+
+// retryPrompt renders the offer to run the failed repos again below the
+// summary.
+func (m model) retryPrompt() string {
+	if !m.offerRetry {
+		return ""
+	}
+
+	return "\n" + m.styles.note.Render(tr(
+		"Press r to run the %d failed repos again, +/- for more or fewer parallel tasks (%s), any other key to quit.",
+		len(m.failedDirs()), m.parallelism(),
+	))
+}
+
+// answerRetry handles a key pressed on the summary while it offers to run
+// the failed repos again: r queues them, + and - change the parallelism
+// for it, and any other key quits.
+func (m model) answerRetry(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "r":
+		m.offerRetry, m.done = false, false
+		m.requeueFailed()
+		return m, tea.Batch(m.progress.SetPercent(m.percent()), m.windowTitle())
+	case "+", "=":
+		m.adjustParallelism(1)
+		return m, nil
+	case "-", "_":
+		m.adjustParallelism(-1)
+		return m, nil
+	}
+
+	m.offerRetry = false
+	return m, m.exit()
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// tapWriter writes the results of the repos as they finish in the Test
+// Anything Protocol for --tap, a test per repo. The plan comes last, since
+// repos can be added along the way. A failed repo can be run again, so its
+// test waits for the end of the run, to only report how it ended up.
+type tapWriter struct {
+	w      io.Writer
+	tests  int
+	failed []repoReport // not written yet, in the order they first failed
+}
+
+func newTAPWriter(w io.Writer) *tapWriter {
+	_, _ = fmt.Fprintln(w, "TAP version 13")
+	return &tapWriter{w: w}
+}
+
+// result records how repo ended: ok, ok with a SKIP directive, or not ok
+// with the task, error and stderr as YAML. A nil writer is a no-op.
+func (t *tapWriter) result(repo repoReport) {
+	if t == nil {
+		return
+	}
+
+	i := slices.IndexFunc(t.failed, func(f repoReport) bool { return f.Path == repo.Path })
+	switch {
+	case repo.Status == "failed" && i >= 0:
+		t.failed[i] = repo
+	case repo.Status == "failed":
+		t.failed = append(t.failed, repo)
+	default:
+		if i >= 0 {
+			t.failed = slices.Delete(t.failed, i, i+1)
+		}
+
+		t.write(repo)
+	}
+}
+
+// write writes the test of repo.
+func (t *tapWriter) write(repo repoReport) {
+	t.tests++
+	switch repo.Status {
+	case "succeeded":
+		_, _ = fmt.Fprintf(t.w, "ok %d - %s\n", t.tests, tapDescription(repo.Path))
+	case "skipped":
+		_, _ = fmt.Fprintf(t.w, "ok %d - %s # SKIP %s\n", t.tests, tapDescription(repo.Path), repo.SkipReason)
+	case "not_run":
+		_, _ = fmt.Fprintf(t.w, "ok %d - %s # SKIP not run\n", t.tests, tapDescription(repo.Path))
+	default:
+		_, _ = fmt.Fprintf(t.w, "not ok %d - %s\n", t.tests, tapDescription(repo.Path))
+		_, _ = fmt.Fprintf(t.w, "  ---\n  task: %s\n  message: %s\n", repo.FailedTask, strconv.Quote(repo.Error))
+		if len(repo.Stderr) > 0 {
+			_, _ = fmt.Fprintf(t.w, "  stderr: |\n    %s\n", strings.Join(repo.Stderr, "\n    "))
+		}
+
+		_, _ = fmt.Fprintln(t.w, "  ...")
+	}
+}
+
+// finish writes the tests of the failed repos, then of the repos of the run
+// that never ran, and the plan, once every repo is done.
+func (t *tapWriter) finish(repos []repoReport) {
+	if t == nil {
+		return
+	}
+
+	for _, repo := range t.failed {
+		t.write(repo)
+	}
+
+	for _, repo := range repos {
+		if repo.Status == "not_run" {
+			t.write(repo)
+		}
+	}
+
+	_, _ = fmt.Fprintf(t.w, "1..%d\n", t.tests)
+}
+
+// tapDescription escapes what TAP would take for a directive in path.
+func tapDescription(path string) string {
+	return strings.ReplaceAll(path, "#", `\#`)
+}
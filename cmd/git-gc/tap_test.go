@@ -0,0 +1,94 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTAPWriter(t *testing.T) {
+	var (
+		ok      = repoReport{Path: "/src/app", Status: "succeeded"}
+		skipped = repoReport{Path: "/src/vendor", Status: "skipped", SkipReason: "excluded by .gitgcignore"}
+		failed  = repoReport{
+			Path:       "/src/lib",
+			Status:     "failed",
+			FailedTask: "fetch",
+			Error:      `exit status 128: "origin" not found`,
+			Stderr:     []string{"fatal: 'origin' does not appear to be a git repository", "fatal: could not read from remote repository."},
+		}
+		stopped = repoReport{Path: "/src/#1", Status: "interrupted", Error: "interrupted"}
+	)
+
+	retried := failed
+	retried.Status = "succeeded"
+
+	tests := []struct {
+		name    string
+		results []repoReport
+		run     []repoReport // every repo of the run, as finish gets them
+		want    string
+	}{
+		{name: "none", want: "TAP version 13\n1..0\n"},
+		{
+			name:    "ok and skipped",
+			results: []repoReport{ok, skipped},
+			want:    "TAP version 13\nok 1 - /src/app\nok 2 - /src/vendor # SKIP excluded by .gitgcignore\n1..2\n",
+		},
+		{
+			name:    "failed last",
+			results: []repoReport{failed, ok},
+			want: "TAP version 13\n" +
+				"ok 1 - /src/app\n" +
+				"not ok 2 - /src/lib\n" +
+				"  ---\n" +
+				"  task: fetch\n" +
+				"  message: \"exit status 128: \\\"origin\\\" not found\"\n" +
+				"  stderr: |\n" +
+				"    fatal: 'origin' does not appear to be a git repository\n" +
+				"    fatal: could not read from remote repository.\n" +
+				"  ...\n" +
+				"1..2\n",
+		},
+		{
+			name:    "failed then retried",
+			results: []repoReport{failed, ok, retried},
+			want:    "TAP version 13\nok 1 - /src/app\nok 2 - /src/lib\n1..2\n",
+		},
+		{
+			name:    "failed twice",
+			results: []repoReport{failed, failed},
+			want:    "TAP version 13\nnot ok 1 - /src/lib\n  ---\n  task: fetch\n  message: \"exit status 128: \\\"origin\\\" not found\"\n  stderr: |\n    fatal: 'origin' does not appear to be a git repository\n    fatal: could not read from remote repository.\n  ...\n1..1\n",
+		},
+		{
+			name:    "not run",
+			results: []repoReport{ok},
+			run:     []repoReport{ok, {Path: "/src/big", Status: "not_run"}},
+			want:    "TAP version 13\nok 1 - /src/app\nok 2 - /src/big # SKIP not run\n1..2\n",
+		},
+		{
+			name:    "interrupted",
+			results: []repoReport{stopped},
+			want:    "TAP version 13\nnot ok 1 - /src/\\#1\n  ---\n  task: \n  message: \"interrupted\"\n  ...\n1..1\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			w := newTAPWriter(&b)
+			for _, repo := range tt.results {
+				w.result(repo)
+			}
+
+			w.finish(tt.run)
+			if b.String() != tt.want {
+				t.Errorf("TAP output\n%s\nwant\n%s", b.String(), tt.want)
+			}
+		})
+	}
+
+	// Without --tap there's no writer
+	var w *tapWriter
+	w.result(ok)
+	w.finish(nil)
+}
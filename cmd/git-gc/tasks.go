@@ -0,0 +1,406 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// task is a single maintenance step run inside a repository. Tasks in a
+// pipeline run sequentially per repo, while repos run in parallel.
+type task struct {
+	name string
+	desc string   // human-readable description used in the summary
+	kind taskKind // which concurrency pool the task runs in
+
+	// timeout bounds how long the task may run in a single repo; zero means
+	// no limit.
+	timeout time.Duration
+
+	// command resolves what to run in dir, giving up when ctx is done. An
+	// invocation without argv means the task has nothing to do in this repo
+	// and is skipped.
+	command func(ctx context.Context, dir string) (invocation, error)
+}
+
+// invocation is a task's command resolved for one repo.
+type invocation struct {
+	argv []string // program first
+	env  []string // added to git-gc's environment, as "KEY=value"
+	note string   // why this command was chosen, shown next to the repo
+
+	// confirm, if set, is a y/n question the user has to answer with yes
+	// before the command runs.
+	confirm string
+
+	// beforeRun, if set, is called right before the command runs, e.g. to
+	// back up what it could destroy, and stops when ctx is done. It returns
+	// a note to add to the task's.
+	beforeRun func(ctx context.Context) (string, error)
+
+	// onSuccess, if set, is called with what the command wrote to stderr
+	// after it exits successfully. It can follow up with more work, which
+	// stops when ctx is done, and returns a note to add to the task's.
+	onSuccess func(ctx context.Context, stderr string) (string, error)
+}
+
+// taskKind groups tasks by the resource they're bound by, so network and disk
+// work can be given independent concurrency limits.
+type taskKind int
+
+const (
+	kindNet taskKind = iota
+	kindDisk
+
+	numTaskKinds
+)
+
+type taskOptions struct {
+	repackFlags []string
+	execCommand string        // shell command template for the exec task
+	strategy    strategy      // how the gc task collects garbage
+	aggrEvery   time.Duration // upgrade normal gc to aggressive this often
+	keepLargest bool          // pass --keep-largest-pack to gc
+	bigPackSize int64         // gc.bigPackThreshold in bytes, 0 to leave git's default
+	annex       annexPolicy
+	pruneGone   pruneGoneMode
+	unmerged    bool    // prune-gone deletes gone branches that aren't merged too
+	looseExpire string  // prune expiry when gc has too many loose objects, "" to only report
+	backups     backups // taken before tasks that could lose data
+	state       *stateStore
+	timeouts    map[string]time.Duration // per task name
+}
+
+var taskNames = []string{"fetch", "remote-prune", "gc", "repack", "fsck", "lfs-prune", "prune-gone", "exec"}
+
+func newTask(name string, opts taskOptions) (task, error) {
+	t, err := baseTask(name, opts)
+	if err != nil {
+		return task{}, err
+	}
+
+	t.timeout = opts.timeouts[name]
+	return t, nil
+}
+
+func baseTask(name string, opts taskOptions) (task, error) {
+	switch name {
+	case "fetch":
+		// git only reports progress to a terminal unless asked to, which
+		// --quiet doesn't keep it from
+		if opts.pruneGone != pruneGoneOff {
+			// Branches only show up as gone once their remote-tracking
+			// branches are pruned
+			return gitTask(name, "fetch", kindNet, "fetch", "--all", "--prune", "--quiet", "--progress"), nil
+		}
+
+		return gitTask(name, "fetch", kindNet, "fetch", "--all", "--quiet", "--progress"), nil
+	case "remote-prune":
+		return task{name: name, desc: "remote prune", kind: kindNet, command: remotePruneCommand}, nil
+	case "gc":
+		return gcTask(opts), nil
+	case "repack":
+		return gitTask(
+			name,
+			"repack",
+			kindDisk,
+			append([]string{"repack", "-a", "-d", "--write-bitmap-index"}, opts.repackFlags...)...,
+		), nil
+	case "fsck":
+		return gitTask(name, "verification", kindDisk, "fsck", "--no-dangling", "--progress"), nil
+	case "lfs-prune":
+		return task{name: name, desc: "lfs prune", kind: kindDisk, command: lfsPruneCommand}, nil
+	case "prune-gone":
+		// Listing the task explicitly without the flag still asks first
+		mode := opts.pruneGone
+		if mode == pruneGoneOff {
+			mode = pruneGoneConfirm
+		}
+
+		return pruneGoneTask(mode, opts.backups, opts.unmerged), nil
+	case "exec":
+		return execTask(opts.execCommand)
+	default:
+		return task{}, fmt.Errorf("unknown task %q (available: %s)", name, strings.Join(taskNames, ", "))
+	}
+}
+
+// parseTasks parses a comma separated list of task names into a pipeline.
+func parseTasks(list string, opts taskOptions) ([]task, error) {
+	var pipeline []task
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		t, err := newTask(name, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		pipeline = append(pipeline, t)
+	}
+
+	if len(pipeline) == 0 {
+		return nil, fmt.Errorf("no tasks given")
+	}
+
+	return pipeline, nil
+}
+
+// parseTaskTimeouts parses a comma separated list of task=duration pairs,
+// e.g. "fetch=2m,gc=30m".
+func parseTaskTimeouts(list string) (map[string]time.Duration, error) {
+	timeouts := make(map[string]time.Duration)
+	for _, pair := range strings.Split(list, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid task timeout %q, expected task=duration", pair)
+		}
+
+		if !slices.Contains(taskNames, name) {
+			return nil, fmt.Errorf("unknown task %q in timeout %q", name, pair)
+		}
+
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid duration for task %q: %w", name, err)
+		}
+
+		timeouts[name] = d
+	}
+
+	return timeouts, nil
+}
+
+// gitTask returns a task that runs git with the same arguments in every repo.
+func gitTask(name, desc string, kind taskKind, args ...string) task {
+	argv := append([]string{gitPath}, args...)
+	return task{
+		name:    name,
+		desc:    desc,
+		kind:    kind,
+		command: func(context.Context, string) (invocation, error) { return invocation{argv: argv}, nil },
+	}
+}
+
+// gcTask returns the gc task for the configured strategy. The adaptive
+// strategy inspects each repo to pick a concrete one.
+func gcTask(opts taskOptions) task {
+	return task{
+		name: "gc",
+		desc: "garbage collection",
+		kind: kindDisk,
+		command: func(ctx context.Context, dir string) (invocation, error) {
+			var (
+				st, note       = opts.strategy, ""
+				lastAggressive = opts.state.lastAggressive(dir)
+				now            = time.Now()
+			)
+			if st == strategyAdaptive {
+				stats, err := inspectRepo(ctx, dir)
+				if err != nil {
+					return invocation{}, err
+				}
+
+				st, note = chooseStrategy(stats, lastAggressive, now)
+				note = fmt.Sprintf("%s gc: %s", st, note)
+			}
+
+			if st == strategyNormal {
+				if ok, why := upgradeToAggressive(opts.aggrEvery, lastAggressive, now); ok {
+					st, note = strategyAggressive, "aggressive gc: "+why
+				}
+			}
+
+			// Aggressively recomputing deltas in a partial clone can make git
+			// lazily fetch the objects it's missing from the promisor remote
+			if st == strategyAggressive {
+				if remote := promisorRemote(ctx, dir); remote != "" {
+					st = strategyNormal
+					note = fmt.Sprintf("normal gc: partial clone of promisor remote %q, skipping aggressive", remote)
+				}
+			}
+
+			argv, canPrune := []string{gitPath}, true
+			if opts.annex == annexSafe && isAnnex(ctx, dir) {
+				canPrune = false
+				argv = append(argv, "-c", "gc.pruneExpire=never")
+				note = joinNotes(note, "git-annex repo, not pruning")
+				if n, err := annexUnused(ctx, dir); err == nil && n > 0 {
+					note = joinNotes(note, fmt.Sprintf("%d unused annex objects, see git annex dropunused", n))
+				}
+			}
+
+			if opts.bigPackSize > 0 {
+				argv = append(argv, "-c", fmt.Sprintf("gc.bigPackThreshold=%d", opts.bigPackSize))
+			}
+
+			argv = append(argv, st.gcArgs()...)
+			if opts.keepLargest {
+				argv = append(argv, "--keep-largest-pack")
+			}
+
+			inv := invocation{
+				argv: argv,
+				note: note,
+				onSuccess: func(ctx context.Context, stderr string) (string, error) {
+					if st == strategyAggressive {
+						_ = opts.state.recordAggressive(dir, time.Now())
+					}
+
+					if !strings.Contains(stderr, tooManyLooseWarning) {
+						return "", nil
+					}
+
+					if opts.looseExpire == "" || !canPrune {
+						return "too many unreachable loose objects, see --prune-loose", nil
+					}
+
+					return pruneLoose(ctx, dir, opts.looseExpire)
+				},
+			}
+
+			// Aggressive gc rewrites every pack, and pruning everything
+			// unreachable leaves nothing to recover from
+			if st == strategyAggressive || (canPrune && opts.looseExpire == "now") {
+				inv.beforeRun = func(ctx context.Context) (string, error) { return opts.backups.create(ctx, dir) }
+			}
+
+			return inv, nil
+		},
+	}
+}
+
+// tooManyLooseWarning is part of the warning gc prints when it's left with
+// more unreachable loose objects than gc.auto, because they're all younger
+// than gc.pruneExpire. Until they expire, every gc --auto runs again without
+// getting rid of them.
+const tooManyLooseWarning = "too many unreachable loose objects"
+
+// pruneLoose follows up on tooManyLooseWarning by pruning the unreachable
+// loose objects older than expire.
+func pruneLoose(ctx context.Context, dir, expire string) (string, error) {
+	cmd, exited := groupCommand(ctx, dir, gitPath, "-C", dir, "prune", "--expire="+expire)
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	err := cmd.Run()
+	exited()
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("could not prune loose objects: %w", contextError(ctx))
+		}
+
+		return "", fmt.Errorf("could not prune loose objects: %w", gitError(err, stderr.String()))
+	}
+
+	return "too many unreachable loose objects, pruned those older than " + expire, nil
+}
+
+// execData is what an exec command template can refer to. Its fields render
+// as quoted references to environment variables holding the values, rather
+// than the values themselves, so that a repo path with spaces, quotes or ;
+// in it can neither break the command nor run other commands.
+type execData struct {
+	Repo string // absolute path of the repo, in GIT_GC_REPO
+	Name string // base name of the repo directory, in GIT_GC_NAME
+}
+
+// newExecData returns the execData of dir, and the environment it refers to.
+func newExecData(dir string) (execData, []string) {
+	data := execData{Repo: shellVar("GIT_GC_REPO"), Name: shellVar("GIT_GC_NAME")}
+	return data, []string{"GIT_GC_REPO=" + dir, "GIT_GC_NAME=" + filepath.Base(dir)}
+}
+
+// shellVar returns a reference to the environment variable name that the
+// shell of shellCommand expands without interpreting the value. cmd expands
+// !name! once the line is parsed, unlike %name%.
+func shellVar(name string) string {
+	if runtime.GOOS == "windows" {
+		return `"!` + name + `!"`
+	}
+
+	return `"$` + name + `"`
+}
+
+// execTask returns a task running an arbitrary shell command in every repo.
+// The command is a text/template rendered with execData.
+func execTask(command string) (task, error) {
+	if strings.TrimSpace(command) == "" {
+		return task{}, errors.New("the exec task needs a command, set it with --exec")
+	}
+
+	tmpl, err := template.New("exec").Option("missingkey=error").Parse(command)
+	if err != nil {
+		return task{}, fmt.Errorf("could not parse exec command: %w", err)
+	}
+
+	return task{
+		name: "exec",
+		desc: "exec",
+		kind: kindDisk,
+		command: func(_ context.Context, dir string) (invocation, error) {
+			data, env := newExecData(dir)
+			var b strings.Builder
+			if err := tmpl.Execute(&b, data); err != nil {
+				return invocation{}, fmt.Errorf("could not render exec command: %w", err)
+			}
+
+			return invocation{argv: shellCommand(b.String()), env: env}, nil
+		},
+	}, nil
+}
+
+// shellCommand wraps command so it's interpreted by the platform's shell,
+// with delayed expansion of !variables! on Windows.
+func shellCommand(command string) []string {
+	if runtime.GOOS == "windows" {
+		return []string{"cmd", "/V:ON", "/C", command}
+	}
+
+	return []string{"sh", "-c", command}
+}
+
+func remotePruneCommand(ctx context.Context, dir string) (invocation, error) {
+	cmd, exited := groupCommand(ctx, dir, gitPath, "-C", dir, "remote")
+	out, err := cmd.Output()
+	exited()
+	if ctx.Err() != nil {
+		return invocation{}, contextError(ctx)
+	}
+
+	if err != nil {
+		return invocation{}, fmt.Errorf("could not list remotes: %w", err)
+	}
+
+	remotes := strings.Fields(string(out))
+	if len(remotes) == 0 {
+		return invocation{}, nil
+	}
+
+	return invocation{argv: append([]string{gitPath, "remote", "prune"}, remotes...)}, nil
+}
+
+func lfsPruneCommand(_ context.Context, dir string) (invocation, error) {
+	// Only repos that have fetched LFS objects have anything to prune, and
+	// skipping the rest avoids failing where git-lfs isn't installed.
+	if _, err := os.Stat(filepath.Join(dir, ".git", "lfs")); err != nil {
+		return invocation{}, nil
+	}
+
+	return invocation{argv: []string{gitPath, "lfs", "prune"}}, nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"runtime"
+	"testing"
+)
+
+// TestExecTaskQuoting checks that repo paths reach the exec command as is,
+// whatever the shell would make of them.
+func TestExecTaskQuoting(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("checks the quoting of sh")
+	}
+
+	task, err := execTask(`printf '%s|%s' {{.Repo}} {{.Name}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, dir := range []string{
+		"/src/app",
+		"/src/my app",
+		"/src/app; touch pwned",
+		`/src/it's "quoted"`,
+		"/src/$(touch pwned)`touch pwned`",
+	} {
+		inv, err := task.command(context.Background(), dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cmd := exec.Command(inv.argv[0], inv.argv[1:]...)
+		cmd.Dir = t.TempDir()
+		cmd.Env = append(os.Environ(), inv.env...)
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("%s: %v", dir, err)
+		}
+
+		if want := dir + "|" + dir[len("/src/"):]; string(out) != want {
+			t.Errorf("command for %q printed %q, want %q", dir, out, want)
+		}
+
+		if _, err := os.Stat(cmd.Dir + "/pwned"); err == nil {
+			t.Errorf("command for %q ran part of the path", dir)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// theme is the palette of the UI. Each color has a variant for light and
+// one for dark terminal backgrounds, which lipgloss picks between by asking
+// the terminal.
+type theme struct {
+	Accent    themeColor `toml:"accent"`    // the spinner and borders
+	Success   themeColor `toml:"success"`   // repos that succeeded
+	Failure   themeColor `toml:"failure"`   // repos that failed
+	Highlight themeColor `toml:"highlight"` // running repos and prompts
+	Warning   themeColor `toml:"warning"`   // the paused banner
+}
+
+// defaultTheme keeps the original colors on dark backgrounds, and darker
+// ones that stay readable on light backgrounds.
+var defaultTheme = theme{
+	Accent:    themeColor{Light: "57", Dark: "63"},
+	Success:   themeColor{Light: "28", Dark: "42"},
+	Failure:   themeColor{Light: "160", Dark: "196"},
+	Highlight: themeColor{Light: "162", Dark: "211"},
+	Warning:   themeColor{Light: "172", Dark: "214"},
+}
+
+// themeColor is a color of the theme, as an ANSI color number or a hex
+// color such as "#ff8700".
+type themeColor struct {
+	Light string `toml:"light"`
+	Dark  string `toml:"dark"`
+}
+
+var hexColor = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// UnmarshalTOML reads a color from the config file: a single color for both
+// backgrounds, or a table with a light and a dark one.
+func (c *themeColor) UnmarshalTOML(v any) error {
+	switch v := v.(type) {
+	case string:
+		c.Light, c.Dark = v, v
+	case map[string]any:
+		for key, value := range v {
+			s, ok := value.(string)
+			switch {
+			case !ok:
+				return fmt.Errorf("color %s must be a string", key)
+			case key == "light":
+				c.Light = s
+			case key == "dark":
+				c.Dark = s
+			default:
+				return fmt.Errorf("unknown color variant %q, use light or dark", key)
+			}
+		}
+	default:
+		return fmt.Errorf("a color must be a string or a table with light and dark, not %T", v)
+	}
+
+	for _, color := range []string{c.Light, c.Dark} {
+		if n, err := strconv.Atoi(color); (err != nil || n < 0 || n > 255) && !hexColor.MatchString(color) && color != "" {
+			return fmt.Errorf("invalid color %q, use an ANSI color number (0-255) or a hex color like #ff8700", color)
+		}
+	}
+
+	return nil
+}
+
+// or fills in the variants c doesn't set from fallback.
+func (c themeColor) or(fallback themeColor) lipgloss.AdaptiveColor {
+	return lipgloss.AdaptiveColor{Light: cmp.Or(c.Light, fallback.Light), Dark: cmp.Or(c.Dark, fallback.Dark)}
+}
+
+func newStyles(t theme) styles {
+	accent := t.Accent.or(defaultTheme.Accent)
+	return styles{
+		start:          lipgloss.NewStyle().Foreground(accent),
+		checkmark:      lipgloss.NewStyle().Foreground(t.Success.or(defaultTheme.Success)).SetString("✓"),
+		cross:          lipgloss.NewStyle().Foreground(t.Failure.or(defaultTheme.Failure)).SetString("✗"),
+		currentDirName: lipgloss.NewStyle().Foreground(t.Highlight.or(defaultTheme.Highlight)),
+		paused:         lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("0")).Background(t.Warning.or(defaultTheme.Warning)),
+		note:           lipgloss.NewStyle().Faint(true),
+		done:           lipgloss.NewStyle().Margin(1, 2),
+		border:         lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(accent).Padding(0, 1),
+	}
+}
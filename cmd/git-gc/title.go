@@ -0,0 +1,18 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/bubbletea"
+)
+
+// windowTitle sets the terminal's title to how many repos are done, with
+// --title, so that the progress shows while the terminal or tmux pane is
+// in the background.
+func (m model) windowTitle() tea.Cmd {
+	if !m.title || m.plain != nil || len(m.directories) == 0 {
+		return nil
+	}
+
+	return tea.SetWindowTitle(fmt.Sprintf("git-gc %d/%d (%d%%)", m.index, len(m.directories), 100*m.index/len(m.directories)))
+}
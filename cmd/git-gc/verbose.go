@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+)
+
+// outputLine is a line a command wrote to stderr, shown with --verbose.
+type outputLine struct {
+	dir  string
+	line string
+}
+
+// lineWriter calls emit with every non-empty line written to it. Of a line
+// redrawn with carriage returns, like git's progress meters, only its final
+// state is emitted.
+type lineWriter struct {
+	emit func(line string)
+	buf  []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+
+		w.emitLine(w.buf[:i])
+		w.buf = w.buf[i+1:]
+	}
+}
+
+// flush emits what's left after the last newline.
+func (w *lineWriter) flush() {
+	w.emitLine(w.buf)
+	w.buf = nil
+}
+
+func (w *lineWriter) emitLine(line []byte) {
+	if i := bytes.LastIndexByte(line, '\r'); i >= 0 {
+		line = line[i+1:]
+	}
+
+	if s := strings.TrimSpace(string(line)); s != "" {
+		w.emit(s)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/charmbracelet/bubbletea"
+)
+
+// procActivity is a running command's heartbeat: when it last wrote output
+// or used CPU time, so commands that stopped doing anything can be told
+// apart from slow ones.
+type procActivity struct {
+	dir        string
+	lastActive atomic.Int64           // unix nanoseconds
+	cpu        time.Duration          // CPU time of its process group at the last check
+	killed     atomic.Pointer[string] // why the user killed it, such as looking hung
+}
+
+func newProcActivity(dir string) *procActivity {
+	a := &procActivity{dir: dir}
+	a.touch()
+	return a
+}
+
+func (a *procActivity) touch() {
+	a.lastActive.Store(time.Now().UnixNano())
+}
+
+func (a *procActivity) idle() time.Duration {
+	return time.Since(time.Unix(0, a.lastActive.Load()))
+}
+
+// activityWriter counts everything written to it as activity.
+type activityWriter struct {
+	w io.Writer
+	a *procActivity
+}
+
+func (aw activityWriter) Write(p []byte) (int, error) {
+	aw.a.touch()
+	return aw.w.Write(p)
+}
+
+// hungTask is a running command that looks hung.
+type hungTask struct {
+	dir  string
+	idle time.Duration
+}
+
+// hungChecked is sent with the commands that look hung, every time the
+// watchdog checks.
+type hungChecked struct {
+	hung []hungTask
+}
+
+// watchdog flags the running commands that did nothing for after.
+type watchdog struct {
+	after time.Duration
+}
+
+// next checks the running commands once a quarter of after passed.
+func (w watchdog) next(r *runner) tea.Cmd {
+	return tea.Tick(w.after/4, func(time.Time) tea.Msg {
+		return hungChecked{hung: r.hung(w.after)}
+	})
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/charmbracelet/bubbletea"
+)
+
+// repoWeights is sent once every repo was measured, with the weight of each
+// in the progress bar: its size, so that one huge repo isn't done at 98%.
+type repoWeights map[string]int64
+
+// minWeight is the least a repo weighs, so that a run of empty repos still
+// moves the progress bar.
+const minWeight = 1 << 20
+
+// measureWeights measures the repos in the background, the same way --order
+// does.
+func measureWeights(dirs []string) tea.Cmd {
+	dirs = slices.Clone(dirs)
+	return func() tea.Msg {
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		weights := make(repoWeights, len(dirs))
+		slots := make(chan struct{}, measurers)
+		for _, dir := range dirs {
+			wg.Add(1)
+			slots <- struct{}{}
+			go func() {
+				defer wg.Done()
+				defer func() { <-slots }()
+
+				stats, _ := inspectRepo(context.Background(), dir)
+				mu.Lock()
+				weights[dir] = max(minWeight, stats.packSize+stats.looseSize)
+				mu.Unlock()
+			}()
+		}
+
+		wg.Wait()
+		return weights
+	}
+}
+
+// percent is how far along the run is: by the weight of the repos that are
+// done once they're measured, or else by their number. Repos added after
+// measuring weigh as much as the average one.
+func (m model) percent() float64 {
+	if len(m.directories) == 0 {
+		return 0
+	}
+
+	if len(m.weights) == 0 {
+		return float64(m.index) / float64(len(m.directories))
+	}
+
+	var sum int64
+	for _, w := range m.weights {
+		sum += w
+	}
+	average := sum / int64(len(m.weights))
+
+	var total, done int64
+	for _, dir := range m.directories {
+		w, ok := m.weights[dir]
+		if !ok {
+			w = average
+		}
+
+		total += w
+		if m.finished[dir] {
+			done += w
+		}
+	}
+
+	return float64(done) / float64(total)
+}